@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"kps-migration-api/config"
 	"net/http"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"czechia.dev/probes"
 	echoSwagger "github.com/swaggo/http-swagger"
@@ -13,39 +16,65 @@ import (
 	"github.com/gorilla/mux"
 )
 
-const alive = true
-
 func isAlive() error {
-	if alive {
+	if alive.Load() {
 		return nil
 	}
 	return errors.New("application is not alive")
 }
 
 func ProcessREST() {
+	shutdownTracing, err := SetupTracing(context.Background())
+	if err != nil {
+		logger.Error("tracing config error", "error", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	// 이전 프로세스가 종료하며 저장한 job
+	if err := restoreCheckpoint(config.Env.JobCheckpointFile); err != nil {
+		logger.Error("job checkpoint error", "error", err)
+	}
+
 	// Start liveness and readiness probes
 	go probes.StartProbes(isAlive)
 
 	// Create HTTP handler
 	r := NewHandler()
 
-	allowedOrigins := handlers.AllowedOrigins([]string{"*"})
-	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	allowedHeaders := handlers.AllowedHeaders([]string{"authorization", "content-type", "accept", "origin", "cache-control", "x-requested-with", "ulang", "accept-language"})
+	allowedOrigins := handlers.AllowedOrigins(config.Env.CorsAllowedOrigins)
+	allowedMethods := handlers.AllowedMethods(config.Env.CorsAllowedMethods)
+	allowedHeaders := handlers.AllowedHeaders(config.Env.CorsAllowedHeaders)
 
 	corsHeader := handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)
 
 	server := &http.Server{
-		Addr:         ":8901",
+		Addr:         config.Env.ListenAddress,
 		Handler:      corsHeader(r),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  config.Env.ReadTimeout,
+		WriteTimeout: config.Env.WriteTimeout,
+		IdleTimeout:  config.Env.IdleTimeout,
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	// Start HTTP server
-	if err := server.ListenAndServe(); err != nil {
-		fmt.Println("Listen error :: ", err)
+	listenErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "address", server.Addr, "tls", config.Env.TlsCertFile != "")
+		if config.Env.TlsCertFile != "" {
+			listenErr <- server.ListenAndServeTLS(config.Env.TlsCertFile, config.Env.TlsKeyFile)
+		} else {
+			listenErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-listenErr:
+		logger.Error("listen error", "error", err)
+	case <-ctx.Done():
+		shutdown(server)
 	}
 }
 func NewHandler() http.Handler {
@@ -57,19 +86,65 @@ func NewHandler() http.Handler {
 	r.HandleFunc("/v1/migration/sync/sync", sync).Methods("POST")
 	//bucket list
 	r.HandleFunc("/v1/migration/operations/list", bucketList).Methods("POST")
+	//job status
+	r.HandleFunc("/v1/migration/jobs/{id}", jobStatus).Methods("GET")
+	//job cancel, pause, resume
+	r.HandleFunc("/v1/migration/jobs/{id}", cancelJob).Methods("DELETE")
+	r.HandleFunc("/v1/migration/jobs/{id}/pause", pauseJob).Methods("POST")
+	r.HandleFunc("/v1/migration/jobs/{id}/resume", resumeJob).Methods("POST")
+	//job progress events
+	r.HandleFunc("/v1/migration/jobs/{id}/events", jobEvents).Methods("GET")
+	//encryption public keys
+	r.HandleFunc("/v1/migration/encryption/jwks.json", encryptionKeys).Methods("GET")
 
 	// Register probes endpoints
-	r.HandleFunc("/actuator/health/liveness", probeRoute(probes.Liveness)).Methods("GET")
-	r.HandleFunc("/actuator/health/readiness", probeRoute(probes.Readiness)).Methods("GET")
+	r.HandleFunc("/actuator/health", health).Methods("GET")
+	r.HandleFunc("/actuator/health/liveness", probeRoute(probes.Liveness, isAlive)).Methods("GET")
+	r.HandleFunc("/actuator/health/readiness", probeRoute(probes.Readiness, isReady)).Methods("GET")
+	// prometheus metrics
+	if config.Env.MetricsEnabled {
+		r.Handle("/metrics", metricsHandler()).Methods("GET")
+	}
+
+	if config.Env.SwaggerEnabled {
+		r.PathPrefix("/swagger").Handler(echoSwagger.WrapHandler).Methods("GET")
+	}
+
+	// 인증 실패를 포함한 모든 요청을 추적, 집계하도록 가장 먼저 등록한다.
+	r.Use(requestIdMiddleware)
+	r.Use(tracingMiddleware)
+	r.Use(metricsMiddleware)
+	// 요청 본문 크기, route별 timeout
+	r.Use(requestLimitMiddleware)
+
+	// OIDC access token 검증 (probe, swagger 제외)
+	verifier, err := newTokenVerifier()
+	if err != nil {
+		logger.Error("auth config error", "error", err)
+		verifier = &tokenVerifier{err: errors.New("authentication is misconfigured")}
+	}
+	if verifier != nil {
+		r.Use(authMiddleware(verifier))
+	} else {
+		logger.Warn("auth is disabled by AuthDisabled: bearer tokens are not validated")
+	}
 
-	r.PathPrefix("/swagger").Handler(echoSwagger.WrapHandler).Methods("GET")
+	// role, group별 API 권한 확인
+	policy, err := loadRbacPolicy(config.Env.RbacPolicyFile)
+	if err != nil {
+		logger.Error("rbac config error", "error", err)
+		policy = &rbacPolicy{err: errors.New("authorization is misconfigured")}
+	}
+	if policy != nil {
+		r.Use(rbacMiddleware(policy))
+	}
 
 	return r
 }
 
-func probeRoute(p *probes.Probe) http.HandlerFunc {
+func probeRoute(p *probes.Probe, check func() error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if p.IsUp() {
+		if p.IsUp() && check() == nil {
 			w.WriteHeader(http.StatusOK)
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
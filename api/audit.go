@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+	"os"
+	gosync "sync"
+	"time"
+)
+
+// 감사 로그 저장소. 파일, stdout 외의 저장소(SIEM 등)는 구현해 SetAuditSink로 등록한다.
+type AuditSink interface {
+	Write(record model.AuditRecord) error
+}
+
+var auditSink = newDefaultAuditSink()
+
+func SetAuditSink(sink AuditSink) {
+	auditSink = sink
+}
+
+// AuditLogFile이 비어 있거나 stdout이면 stdout에, 아니면 파일 끝에 JSON lines로 기록한다.
+func newDefaultAuditSink() AuditSink {
+	file := config.Env.AuditLogFile
+	if file == "" || file == "stdout" {
+		return newJsonLineAuditSink(os.Stdout)
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error("audit log error, writing audit records to stdout", "error", err)
+		return newJsonLineAuditSink(os.Stdout)
+	}
+	return newJsonLineAuditSink(f)
+}
+
+type jsonLineAuditSink struct {
+	mu gosync.Mutex
+	w  io.Writer
+}
+
+func newJsonLineAuditSink(w io.Writer) *jsonLineAuditSink {
+	return &jsonLineAuditSink{w: w}
+}
+
+// 레코드 하나를 한 번의 Write로 기록해 줄이 섞이지 않게 한다.
+func (s *jsonLineAuditSink) Write(record model.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func writeAudit(record model.AuditRecord) {
+	record.Time = time.Now().UTC()
+	if err := auditSink.Write(record); err != nil {
+		logger.Error("audit log error", "error", err)
+	}
+}
+
+// 요청 시각과 호출자를 채운 감사 기록
+func newAuditRecord(r *http.Request, operation string) model.AuditRecord {
+	record := model.AuditRecord{
+		Operation:  operation,
+		RemoteAddr: r.RemoteAddr,
+		StartTime:  time.Now().UTC(),
+	}
+	if c, ok := callerFromContext(r.Context()); ok {
+		record.Caller = &model.AuditCaller{Subject: c.Subject, Username: c.Username, Email: c.Email}
+	}
+	return record
+}
+
+// 인증 정보 없이 스토리지 종류, 호스트, bucket만 기록한다.
+func auditStorage(storage model.StorageConfig) *model.AuditStorage {
+	return &model.AuditStorage{
+		StorageType: storage.StorageType,
+		Endpoint:    storageEndpoint(storage),
+		Bucket:      storage.Bucket,
+	}
+}
+
+// 요청 결과를 기록한다. message는 인증 정보를 가린 값이어야 한다.
+func auditOutcome(record model.AuditRecord, status string, code string, message string) {
+	record.EndTime = time.Now().UTC()
+	record.Status = status
+	record.ErrorCode = code
+	record.Error = message
+	writeAudit(record)
+}
+
+// 작업을 시작하기 전에 실패한 요청을 기록한다.
+func auditRejected(record model.AuditRecord, code string, message string) {
+	status := model.AuditStatusRejected
+	if code == errCodeForbidden {
+		status = model.AuditStatusDenied
+	}
+	auditOutcome(record, status, code, message)
+}
+
+// 종료된 job의 결과와 전송량을 기록한다.
+func (j *job) writeAudit(status model.JobStatus, progress model.JobProgress) {
+	j.mu.Lock()
+	if j.auditRecord == nil {
+		j.mu.Unlock()
+		return
+	}
+	record := *j.auditRecord
+	j.mu.Unlock()
+
+	if status.EndTime != nil {
+		record.EndTime = *status.EndTime
+	}
+	record.Status = string(status.State)
+	record.ErrorCode = status.ErrorCode
+	record.Error = status.Error
+	record.Bytes = progress.Bytes
+	record.Objects = progress.Transfers
+	record.Deletes = progress.Deletes
+	writeAudit(record)
+}
+
+func (j *job) setAuditRecord(record model.AuditRecord) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record.JobId = j.id
+	j.auditRecord = &record
+}
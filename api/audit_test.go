@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+type recordingAuditSink struct {
+	mu      gosync.Mutex
+	records []model.AuditRecord
+}
+
+func (s *recordingAuditSink) Write(record model.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingAuditSink) snapshot() []model.AuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.AuditRecord(nil), s.records...)
+}
+
+func withAuditSink(t *testing.T) *recordingAuditSink {
+	t.Helper()
+	sink := &recordingAuditSink{}
+	old := auditSink
+	t.Cleanup(func() { auditSink = old })
+	SetAuditSink(sink)
+	return sink
+}
+
+// method별 응답을 돌려주는 rclone mock
+func withRcloneResponses(t *testing.T, responses map[string]string) {
+	t.Helper()
+	oldInit, oldRPC, oldInterval := rcloneInitialize, rcloneRPC, jobPollInterval
+	t.Cleanup(func() {
+		stopJobRoutines(t)
+		rcloneInitialize, rcloneRPC, jobPollInterval = oldInit, oldRPC, oldInterval
+	})
+	rcloneInitialize = func() {}
+	rcloneRPC = func(method, in string) (string, int) {
+		if out, ok := responses[method]; ok {
+			return out, http.StatusOK
+		}
+		return `{"error":"unexpected method"}`, http.StatusInternalServerError
+	}
+	jobPollInterval = 10 * time.Millisecond
+}
+
+func withTestCaller(r *http.Request, subject string) *http.Request {
+	c := &caller{Subject: subject, Username: "alice", Email: "alice@example.com"}
+	return r.WithContext(context.WithValue(r.Context(), callerContextKey{}, c))
+}
+
+func TestJsonLineAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newJsonLineAuditSink(&buf)
+	sink.Write(model.AuditRecord{Operation: "copy", Status: model.AuditStatusSuccess})
+	sink.Write(model.AuditRecord{Operation: "list", Status: model.AuditStatusError})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var record model.AuditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record.Operation != "list" {
+		t.Fatalf("unexpected line %q (err=%v)", lines[1], err)
+	}
+}
+
+func TestCopy_AuditsJobOutcome(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	sink := withAuditSink(t)
+	withRcloneResponses(t, map[string]string{
+		"sync/copy":  `{"jobid":7}`,
+		"job/status": `{"id":7,"finished":true,"success":true,"endTime":"2026-01-01T00:00:00Z"}`,
+		"core/stats": `{"bytes":2048,"transfers":3,"deletes":1}`,
+	})
+
+	body, _ := json.Marshal(model.SyncConfig{
+		Src: model.StorageConfig{StorageType: "s3", Endpoint: "https://src.example.com", AccessKeyId: "srcKey", SecretAccessKey: "srcSecret", Bucket: "a"},
+		Dst: model.StorageConfig{StorageType: "s3", Endpoint: "https://dst.example.com", AccessKeyId: "dstKey", SecretAccessKey: "dstSecret", Bucket: "b"},
+	})
+	req := withTestCaller(httptest.NewRequest(http.MethodPost, "/v1/migration/sync/copy", bytes.NewReader(body)), "user-1")
+	w := httptest.NewRecorder()
+	copy(w, req)
+
+	var accepted model.JobAccepted
+	decodeResponse(t, w.Body, &accepted)
+	j, ok := jobs.get(accepted.JobId)
+	if !ok {
+		t.Fatalf("job %q not found", accepted.JobId)
+	}
+	t.Cleanup(func() { jobs.remove(j.id) })
+
+	// watch가 job을 종료하고 기록할 때까지 기다린다.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	j.refresh(7)
+
+	records := sink.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Operation != "copy" || record.JobId != j.id || record.Status != model.AuditStatusSuccess {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	if record.Caller == nil || record.Caller.Subject != "user-1" {
+		t.Fatalf("expected caller, got %+v", record.Caller)
+	}
+	if record.Src.Endpoint != "src.example.com" || record.Src.Bucket != "a" || record.Dst.Endpoint != "dst.example.com" {
+		t.Fatalf("unexpected storages: %+v %+v", record.Src, record.Dst)
+	}
+	if record.Bytes != 2048 || record.Objects != 3 || record.Deletes != 1 {
+		t.Fatalf("unexpected stats: %+v", record)
+	}
+	line, _ := json.Marshal(record)
+	for _, secret := range []string{"srcKey", "srcSecret", "dstKey", "dstSecret"} {
+		if strings.Contains(string(line), secret) {
+			t.Fatalf("audit record leaks %q: %s", secret, line)
+		}
+	}
+}
+
+func TestCopy_AuditsRejectedRequest(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	sink := withAuditSink(t)
+
+	body := `{"src":{"storageType":"ftp","bucket":"a"},"dst":{"storageType":"local","bucket":"/b"},"dryRun":true}`
+	req := withTestCaller(httptest.NewRequest(http.MethodPost, "/v1/migration/sync/copy", strings.NewReader(body)), "user-1")
+	copy(httptest.NewRecorder(), req)
+
+	records := sink.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Status != model.AuditStatusRejected || record.ErrorCode != errCodeInvalidStorage || !record.DryRun {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+}
+
+func TestBucketList_AuditsOutcome(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	sink := withAuditSink(t)
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		setStatus(http.StatusForbidden)
+		setOut(`{"error":"AccessDenied: secret_access_key=\"hunter2\" denied","status":403}`)
+
+		body := `{"storageType":"s3","endpoint":"http://10.0.0.5:7480","accessKeyId":"k","secretAccessKey":"hunter2"}`
+		bucketList(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", strings.NewReader(body)))
+	})
+
+	records := sink.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Operation != "list" || record.Status != model.AuditStatusError || record.ErrorCode != errCodePermissionDenied {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	if record.Src.Endpoint != "10.0.0.5" || strings.Contains(record.Error, "hunter2") {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+}
@@ -1,17 +1,14 @@
 package api
 
 import (
-	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
-	"crypto/rsa"
+	"crypto/rand"
 	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"kps-migration-api/config"
@@ -35,36 +32,32 @@ func hmacEncode(json []byte) string {
 	return signature
 }
 
+// PrivateKey 설정의 키로 복호화한다. kid가 있는 요청은 rsaDecodeKid를 사용한다.
 func rsaDecode(json []byte) ([]byte, error) {
-	spkiBlock, _ := pem.Decode([]byte(config.Env.PrivateKey))
-	var spkiKey *rsa.PrivateKey
-	priInterface, err := x509.ParsePKCS8PrivateKey(spkiBlock.Bytes)
+	spkiKey, err := parsePrivateKey([]byte(config.Env.PrivateKey))
 	if err != nil {
 		return nil, err
 	}
-	spkiKey = priInterface.(*rsa.PrivateKey)
-	decryptSignature, err := spkiKey.Decrypt(nil, json, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	decryptSignature, err := rsaDecrypt(spkiKey, json)
 	if err != nil {
-		fmt.Println(err)
+		logger.Warn("rsa decryption failed", "error", err)
 		return nil, errors.New("don't decode rsa Data")
 	}
 	return decryptSignature, nil
 }
 
+// 요청 timestamp가 PayloadMaxAge보다 오래되었거나 PayloadMaxSkew보다 미래이면 유효하지 않다.
 func vaildTimestampCheck(originTimestamp string, currentTimestamp string) bool {
-	current, err := strconv.Atoi(currentTimestamp)
+	current, err := strconv.ParseInt(currentTimestamp, 10, 64)
 	if err != nil {
-		panic(err)
+		return false
 	}
-	original, err := strconv.Atoi(originTimestamp)
+	original, err := strconv.ParseInt(originTimestamp, 10, 64)
 	if err != nil {
-		panic(err)
-	}
-	if (current - original) > 5*60*1000 {
 		return false
-	} else {
-		return true
 	}
+	age := current - original
+	return age <= payloadMaxAge().Milliseconds() && age >= -payloadMaxSkew().Milliseconds()
 }
 
 // aes decode
@@ -105,11 +98,28 @@ func pkcs7Unpad(data []byte) ([]byte, error) {
 var (
 	rsaDecodeFunc           = rsaDecode
 	aesDecryptFunc          = aesDecrypt
+	aesGcmDecryptFunc       = aesGcmDecrypt
 	currentTimestampFunc    = currentTimestamp
 	hmacEncodeFunc          = hmacEncode
 	vaildTimestampCheckFunc = vaildTimestampCheck
 )
 
+// 복호화 실패 원인(base64, rsa, padding, 인증 태그, hmac)은 구분하지 않고 같은 오류로 반환한다.
+var errPayloadDecryption = errors.New("payload decryption failed")
+
+// AES-CBC 복호화 결과의 hmac 불일치. metric에서만 구분한다.
+var errIntegrity = errors.New("integrity verification failed")
+
+// 요청 timestamp가 허용 범위를 벗어났다.
+var errRequestExpired = errors.New("your request has expired")
+
+// 요청에서 복호화한 AES 키와 암호화 방식, nonce. 응답도 같은 방식으로 암호화하고 요청의 nonce에 묶는다.
+type payloadKey struct {
+	alg    string
+	aesKey []byte
+	nonce  string
+}
+
 // 복호화 구현
 // 1. base64 decode function 호출
 // 2. 키를 rsa decode funcion 호출
@@ -118,65 +128,256 @@ var (
 // 4. 객체의 timestamp를 확인하여 timestamp vaild function 호출
 // 5. 이후 기능 수행(struct에 mapping이 불가능 하다면 잘못된 정보가 넘어왔다 생각하고 error 발생.)
 func decodePayload[T any](payload model.HybridPayload) (T, error) {
+	data, _, err := decodeHybridPayload[T](payload)
+	return data, err
+}
+
+// decodePayload와 같고, 응답 암호화에 사용할 수 있도록 복호화한 AES 키를 함께 반환한다.
+// alg가 AES-256-GCM이면 GCM 인증 태그로 무결성을 확인하고 timestamp를 associated data로 검증한다.
+func decodeHybridPayload[T any](payload model.HybridPayload) (T, *payloadKey, error) {
+	var t T
 	currentTimestamp := currentTimestampFunc()
 
+	alg := payload.Alg
+	if alg == "" {
+		alg = model.PayloadAlgAesCbc
+	}
+	if alg != model.PayloadAlgAesCbc && alg != model.PayloadAlgAesGcm {
+		payloadFailuresTotal.WithLabelValues("unsupported_alg").Inc()
+		return t, nil, fmt.Errorf("unsupported alg %q", payload.Alg)
+	}
+
 	//1. base64 decode
 	encodedAesData, err := base64.StdEncoding.DecodeString(payload.EncryptedData)
 	if err != nil {
-		var t T
-		return t, errors.New("base64 decode failed")
+		return t, nil, payloadDecryptionFailed("decryption")
 	}
 	encodedAesKey, err := base64.StdEncoding.DecodeString(payload.EncryptedKey)
 	if err != nil {
-		var t T
-		return t, errors.New("base64 decode failed")
+		return t, nil, payloadDecryptionFailed("decryption")
 	}
-	//iv := payload.IV
 	iv, err := base64.StdEncoding.DecodeString(payload.IV)
 	if err != nil {
-		var t T
-		return t, errors.New("base64 decode failed")
+		return t, nil, payloadDecryptionFailed("decryption")
 	}
 
 	// 2. 키를 rsa decode funcion 호출
-	decodedAesKey, err := rsaDecodeFunc(encodedAesKey)
+	decodedAesKey, err := rsaDecodeKid(payload.Kid, encodedAesKey)
 	if err != nil {
-		var t T
-		return t, err
+		return t, nil, payloadDecryptionFailed("decryption")
 	}
 
-	// 3. 객체를 aes decode funcion 호출
-	decodedData, err := aesDecryptFunc(encodedAesData, decodedAesKey, []byte(iv))
+	// 3. 객체를 aes decode funcion 호출 하고 무결성 확인
+	var decoded model.PayloadModelWithoutHMAC[T]
+	if alg == model.PayloadAlgAesGcm {
+		decoded.Data, err = decodeGcmData[T](encodedAesData, decodedAesKey, iv, payload.Timestamp, payload.Nonce)
+		decoded.Timestamp = payload.Timestamp
+		decoded.Nonce = payload.Nonce
+	} else {
+		decoded, err = decodeCbcData[T](encodedAesData, decodedAesKey, iv)
+	}
+	if errors.Is(err, errIntegrity) {
+		return t, nil, payloadDecryptionFailed("hmac")
+	}
 	if err != nil {
-		var t T
-		return t, err
+		return t, nil, payloadDecryptionFailed("decryption")
 	}
 
-	var PayloadModelWithHMAC model.PayloadModelWithHMAC[T]
+	// 4. 객체의 timestamp를 확인하여 timestamp vaild function 호출
+	if !vaildTimestampCheckFunc(decoded.Timestamp, currentTimestamp) {
+		// 유효하지 않으므로 기한이 지난 요청이라 판단하고 에러 발생 후 리턴
+		payloadFailuresTotal.WithLabelValues("expired").Inc()
+		return t, nil, errRequestExpired
+	}
 
-	json.Unmarshal(decodedData, &PayloadModelWithHMAC)
+	// 5. nonce를 등록하여 같은 요청의 재전송을 거부
+	timestampMillis, _ := strconv.ParseInt(decoded.Timestamp, 10, 64)
+	if err := checkNonce(decoded.Nonce, timestampMillis); err != nil {
+		if errors.Is(err, errReplayedRequest) {
+			payloadFailuresTotal.WithLabelValues("replay").Inc()
+		} else {
+			payloadFailuresTotal.WithLabelValues("nonce").Inc()
+		}
+		return t, nil, err
+	}
 
-	hmac_data := PayloadModelWithHMAC.Hmac_data
+	return decoded.Data, &payloadKey{alg: alg, aesKey: decodedAesKey, nonce: decoded.Nonce}, nil
+}
+
+// 복호화 실패 원인을 집계하고, 응답에는 원인을 구분하지 않는 같은 오류를 반환한다.
+func payloadDecryptionFailed(reason string) error {
+	payloadFailuresTotal.WithLabelValues(reason).Inc()
+	return errPayloadDecryption
+}
 
+// AES-CBC: 복호화한 {data, timestamp, nonce, hmac_data}의 hmac을 확인한다.
+func decodeCbcData[T any](cipherText, key, iv []byte) (model.PayloadModelWithoutHMAC[T], error) {
 	var payloadModelWithoutHMAC model.PayloadModelWithoutHMAC[T]
+	decodedData, err := aesDecryptFunc(cipherText, key, iv)
+	if err != nil {
+		return payloadModelWithoutHMAC, err
+	}
+
+	var PayloadModelWithHMAC model.PayloadModelWithHMAC[T]
+	if err := json.Unmarshal(decodedData, &PayloadModelWithHMAC); err != nil {
+		return payloadModelWithoutHMAC, err
+	}
+	hmac_data := PayloadModelWithHMAC.Hmac_data
+
 	json.Unmarshal(decodedData, &payloadModelWithoutHMAC)
 	byt, err := json.Marshal(payloadModelWithoutHMAC)
 	if err != nil {
-		var t T
-		return t, errors.New("json parsing failed")
+		return payloadModelWithoutHMAC, err
 	}
-	// 4. 객체를 hmac encode function 호출 하여 넘어온 hmac_data와 비교 분석
-	if hmacEncodeFunc(byt) != hmac_data {
-		var t T
-		return t, errors.New("integrity verification failed")
-		//틀리므로 에러 발생 후 리턴
+	// 객체를 hmac encode function 호출 하여 넘어온 hmac_data와 비교 분석
+	if !hmac.Equal([]byte(hmacEncodeFunc(byt)), []byte(hmac_data)) {
+		return payloadModelWithoutHMAC, errIntegrity
 	}
-	// 5. 객체의 timestamp를 확인하여 timestamp vaild function 호출
-	if !vaildTimestampCheckFunc(payloadModelWithoutHMAC.Timestamp, currentTimestamp) {
-		// 유효하지 않으므로 기한이 지난 요청이라 판단하고 에러 발생 후 리턴
-		var t T
-		return t, errors.New("your request has expired")
+	return payloadModelWithoutHMAC, nil
+}
+
+// AES-256-GCM associated data. timestamp는 숫자이므로 첫 "."으로 nonce와 구분된다.
+func gcmAssociatedData(timestamp string, nonce string) []byte {
+	return []byte(timestamp + "." + nonce)
+}
+
+// AES-256-GCM: 암호문은 data 객체의 json이고 timestamp와 nonce는 associated data로 인증한다.
+func decodeGcmData[T any](cipherText, key, iv []byte, timestamp string, nonce string) (T, error) {
+	var t T
+	decodedData, err := aesGcmDecryptFunc(cipherText, key, iv, gcmAssociatedData(timestamp, nonce))
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(decodedData, &t); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("AES-256-GCM requires a 32 byte key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// aes gcm decode
+func aesGcmDecrypt(cipherText, key, nonce, additionalData []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	return gcm.Open(nil, nonce, cipherText, additionalData)
+}
+
+// aes gcm encode. 매번 새 nonce를 만든다.
+func aesGcmEncrypt(plainText, key, additionalData []byte) ([]byte, []byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return gcm.Seal(nil, nonce, plainText, additionalData), nonce, nil
+}
+
+// PKCS7 패딩 추가
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	paddingLen := blockSize - len(data)%blockSize
+	padding := make([]byte, paddingLen)
+	for i := range padding {
+		padding[i] = byte(paddingLen)
+	}
+	return append(data, padding...)
+}
+
+// aes encode. 매번 새 iv를 만든다.
+func aesEncrypt(plainText, key []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, err
+	}
+
+	plainText = pkcs7Pad(plainText, aes.BlockSize)
+	cipherText := make([]byte, len(plainText))
+	mode := cipher.NewCBCEncrypter(block, iv)
+	mode.CryptBlocks(cipherText, plainText)
+
+	return cipherText, iv, nil
+}
+
+// 암호화 구현 (decodePayload의 역순). 요청과 같은 alg를 사용한다.
+// AES-CBC
+// 1. 객체와 timestamp를 hmac encode function 호출
+// 2. hmac_data를 포함한 객체를 요청의 aes 키로 aes encode
+// 3. iv와 암호문을 base64 encode
+// AES-256-GCM
+// 1. 객체를 timestamp와 요청의 nonce를 associated data로 aes gcm encode
+// 2. gcm nonce와 암호문을 base64 encode
+func encodePayload[T any](data T, key *payloadKey) (model.EncryptedPayload, error) {
+	timestamp := currentTimestampFunc()
+
+	if key.alg == model.PayloadAlgAesGcm {
+		byt, err := json.Marshal(data)
+		if err != nil {
+			return model.EncryptedPayload{}, errors.New("json parsing failed")
+		}
+		cipherText, nonce, err := aesGcmEncrypt(byt, key.aesKey, gcmAssociatedData(timestamp, key.nonce))
+		if err != nil {
+			return model.EncryptedPayload{}, err
+		}
+		return model.EncryptedPayload{
+			Alg:           key.alg,
+			IV:            base64.StdEncoding.EncodeToString(nonce),
+			EncryptedData: base64.StdEncoding.EncodeToString(cipherText),
+			Timestamp:     timestamp,
+		}, nil
+	}
+
+	payloadModelWithoutHMAC := model.PayloadModelWithoutHMAC[T]{
+		Data:      data,
+		Timestamp: timestamp,
+		Nonce:     key.nonce,
+	}
+	byt, err := json.Marshal(payloadModelWithoutHMAC)
+	if err != nil {
+		return model.EncryptedPayload{}, errors.New("json parsing failed")
+	}
+
+	// 1. 객체를 hmac encode function 호출
+	payloadModelWithHMAC := model.PayloadModelWithHMAC[T]{
+		Data:      data,
+		Timestamp: timestamp,
+		Nonce:     key.nonce,
+		Hmac_data: hmacEncodeFunc(byt),
+	}
+	byt, err = json.Marshal(payloadModelWithHMAC)
+	if err != nil {
+		return model.EncryptedPayload{}, errors.New("json parsing failed")
+	}
+
+	// 2. aes encode
+	cipherText, iv, err := aesEncrypt(byt, key.aesKey)
+	if err != nil {
+		return model.EncryptedPayload{}, err
 	}
 
-	return PayloadModelWithHMAC.Data, nil
+	// 3. base64 encode
+	return model.EncryptedPayload{
+		IV:            base64.StdEncoding.EncodeToString(iv),
+		EncryptedData: base64.StdEncoding.EncodeToString(cipherText),
+	}, nil
 }
@@ -106,8 +106,8 @@ func TestDecodePayload_Base64ErrorOnData(t *testing.T) {
 		IV:            "",
 	}
 	_, err := decodePayload[struct{}](payload)
-	if err == nil || err.Error() != "base64 decode failed" {
-		t.Fatalf("expected base64 decode failed error, got %v", err)
+	if !errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected payload decryption failed error, got %v", err)
 	}
 }
 
@@ -143,8 +143,8 @@ func TestDecodePayload_RsaDecodeError(t *testing.T) {
 	vaildTimestampCheckFunc = func(o, c string) bool { return true }
 
 	_, err := decodePayload[struct{}](payload)
-	if err == nil || err.Error() != "rsa fail" {
-		t.Fatalf("expected rsa fail error, got %v", err)
+	if !errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected payload decryption failed error, got %v", err)
 	}
 }
 
@@ -194,8 +194,8 @@ func TestDecodePayload_HmacMismatch(t *testing.T) {
 	vaildTimestampCheckFunc = func(o, c string) bool { return true }
 
 	_, err := decodePayload[inner](payload)
-	if err == nil || err.Error() != "integrity verification failed" {
-		t.Fatalf("expected integrity verification failed, got %v", err)
+	if !errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected payload decryption failed error, got %v", err)
 	}
 }
 
@@ -221,6 +221,7 @@ func TestDecodePayload_Success(t *testing.T) {
 	}()
 
 	rsaDecodeFunc = func(b []byte) ([]byte, error) { return []byte("key"), nil }
+	withMemoryNonceStore(t)
 
 	type inner struct {
 		Value string `json:"value"`
@@ -228,6 +229,7 @@ func TestDecodePayload_Success(t *testing.T) {
 	type payloadWithHMAC struct {
 		Data      inner  `json:"data"`
 		Timestamp string `json:"timestamp"`
+		Nonce     string `json:"nonce"`
 		Hmac_data string `json:"hmac_data"`
 	}
 
@@ -238,6 +240,7 @@ func TestDecodePayload_Success(t *testing.T) {
 	decodedJSON, _ := json.Marshal(payloadWithHMAC{
 		Data:      inner{Value: "ok"},
 		Timestamp: "0",
+		Nonce:     "nonce-0123456789abcdef",
 		Hmac_data: "good-hmac",
 	})
 
@@ -378,3 +381,188 @@ func TestDecodePayload_Expired(t *testing.T) {
 		t.Fatalf("expected your request has expired error, got %v", err)
 	}
 }
+
+func TestEncodePayload_RoundTrip(t *testing.T) {
+	if config.Env == nil {
+		t.Skip("config.Env is nil")
+	}
+	oldHmacKey := config.Env.HmacKey
+	defer func() { config.Env.HmacKey = oldHmacKey }()
+	config.Env.HmacKey = "test-hmac-key"
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	encrypted, err := encodePayload(model.JobAccepted{JobId: "abc"}, &payloadKey{alg: model.PayloadAlgAesCbc, aesKey: key})
+	if err != nil {
+		t.Fatalf("encodePayload error: %v", err)
+	}
+
+	cipherText, _ := base64.StdEncoding.DecodeString(encrypted.EncryptedData)
+	iv, _ := base64.StdEncoding.DecodeString(encrypted.IV)
+	plainText, err := aesDecrypt(cipherText, key, iv)
+	if err != nil {
+		t.Fatalf("aesDecrypt error: %v", err)
+	}
+
+	var withHMAC model.PayloadModelWithHMAC[model.JobAccepted]
+	if err := json.Unmarshal(plainText, &withHMAC); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	byt, _ := json.Marshal(model.PayloadModelWithoutHMAC[model.JobAccepted]{Data: withHMAC.Data, Timestamp: withHMAC.Timestamp})
+	if hmacEncode(byt) != withHMAC.Hmac_data {
+		t.Fatalf("expected hmac to verify")
+	}
+	if withHMAC.Data.JobId != "abc" || withHMAC.Timestamp == "" {
+		t.Fatalf("unexpected payload %+v", withHMAC)
+	}
+}
+
+func TestAESEncrypt_NewIVEachTime(t *testing.T) {
+	key := make([]byte, 32)
+	_, iv1, err := aesEncrypt([]byte("data"), key)
+	if err != nil {
+		t.Fatalf("aesEncrypt error: %v", err)
+	}
+	_, iv2, _ := aesEncrypt([]byte("data"), key)
+	if string(iv1) == string(iv2) {
+		t.Fatalf("expected a new iv for each encryption")
+	}
+}
+
+func gcmPayload(t *testing.T, key []byte, data interface{}, timestamp string) model.HybridPayload {
+	t.Helper()
+	requestNonce := "nonce-" + timestamp + "-0123456789"
+	byt, _ := json.Marshal(data)
+	cipherText, nonce, err := aesGcmEncrypt(byt, key, gcmAssociatedData(timestamp, requestNonce))
+	if err != nil {
+		t.Fatalf("aesGcmEncrypt error: %v", err)
+	}
+	return model.HybridPayload{
+		Alg:           model.PayloadAlgAesGcm,
+		EncryptedKey:  base64.StdEncoding.EncodeToString([]byte("wrapped-key")),
+		IV:            base64.StdEncoding.EncodeToString(nonce),
+		EncryptedData: base64.StdEncoding.EncodeToString(cipherText),
+		Timestamp:     timestamp,
+		Nonce:         requestNonce,
+	}
+}
+
+func TestDecodePayload_Gcm(t *testing.T) {
+	oldRsa := rsaDecodeFunc
+	oldValid := vaildTimestampCheckFunc
+	defer func() {
+		rsaDecodeFunc = oldRsa
+		vaildTimestampCheckFunc = oldValid
+	}()
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	rsaDecodeFunc = func(b []byte) ([]byte, error) { return key, nil }
+	withMemoryNonceStore(t)
+	checkedTimestamp := ""
+	vaildTimestampCheckFunc = func(o, c string) bool {
+		checkedTimestamp = o
+		return true
+	}
+
+	type inner struct {
+		Value string `json:"value"`
+	}
+	timestamp := currentTimestamp()
+	payload := gcmPayload(t, key, inner{Value: "ok"}, timestamp)
+
+	out, payloadKey, err := decodeHybridPayload[inner](payload)
+	if err != nil {
+		t.Fatalf("expected success, got error %v", err)
+	}
+	if out.Value != "ok" || checkedTimestamp != timestamp {
+		t.Fatalf("unexpected result %+v, timestamp %q", out, checkedTimestamp)
+	}
+	if payloadKey.alg != model.PayloadAlgAesGcm || string(payloadKey.aesKey) != string(key) {
+		t.Fatalf("expected the request key and alg, got %+v", payloadKey)
+	}
+
+	// associated data로 인증된 timestamp를 바꾸면 복호화에 실패한다.
+	tampered := payload
+	tampered.Timestamp = "9999999999999"
+	if _, err := decodePayload[inner](tampered); !errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected payload decryption failed for tampered timestamp, got %v", err)
+	}
+	tampered = payload
+	tampered.Nonce = "another-nonce-0123456789"
+	if _, err := decodePayload[inner](tampered); !errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected payload decryption failed for tampered nonce, got %v", err)
+	}
+
+	// 같은 요청을 다시 보내면 거부한다.
+	if _, err := decodePayload[inner](payload); !errors.Is(err, errReplayedRequest) {
+		t.Fatalf("expected replayed request to be rejected, got %v", err)
+	}
+}
+
+func TestDecodePayload_UniformDecryptionError(t *testing.T) {
+	oldRsa := rsaDecodeFunc
+	oldValid := vaildTimestampCheckFunc
+	defer func() {
+		rsaDecodeFunc = oldRsa
+		vaildTimestampCheckFunc = oldValid
+	}()
+
+	key := make([]byte, 32)
+	rsaDecodeFunc = func(b []byte) ([]byte, error) { return key, nil }
+	vaildTimestampCheckFunc = func(o, c string) bool { return true }
+
+	encoded := base64.StdEncoding.EncodeToString
+	badPadding, iv, _ := aesEncrypt([]byte("x"), key)
+	badPadding[len(badPadding)-1] ^= 0xff
+	goodPadding, iv2, _ := aesEncrypt([]byte(`{"data":{},"timestamp":"0","hmac_data":"bad"}`), key)
+	gcm := gcmPayload(t, key, struct{}{}, "0")
+	gcmCipher, _ := base64.StdEncoding.DecodeString(gcm.EncryptedData)
+	gcmCipher[0] ^= 0xff
+
+	tests := map[string]model.HybridPayload{
+		"bad padding":   {EncryptedKey: encoded(key), IV: encoded(iv), EncryptedData: encoded(badPadding)},
+		"bad hmac":      {EncryptedKey: encoded(key), IV: encoded(iv2), EncryptedData: encoded(goodPadding)},
+		"gcm bad tag":   {Alg: gcm.Alg, Timestamp: gcm.Timestamp, EncryptedKey: gcm.EncryptedKey, IV: gcm.IV, EncryptedData: encoded(gcmCipher)},
+		"gcm bad nonce": {Alg: gcm.Alg, Timestamp: gcm.Timestamp, EncryptedKey: gcm.EncryptedKey, IV: encoded(iv), EncryptedData: gcm.EncryptedData},
+	}
+	for name, payload := range tests {
+		_, err := decodePayload[struct{}](payload)
+		if err != errPayloadDecryption {
+			t.Errorf("%s: expected %v, got %v", name, errPayloadDecryption, err)
+		}
+	}
+}
+
+func TestDecodePayload_UnsupportedAlg(t *testing.T) {
+	_, err := decodePayload[struct{}](model.HybridPayload{Alg: "DES"})
+	if err == nil || errors.Is(err, errPayloadDecryption) {
+		t.Fatalf("expected unsupported alg error, got %v", err)
+	}
+}
+
+func TestEncodePayload_Gcm(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	encrypted, err := encodePayload(model.JobAccepted{JobId: "abc"}, &payloadKey{alg: model.PayloadAlgAesGcm, aesKey: key, nonce: "request-nonce"})
+	if err != nil {
+		t.Fatalf("encodePayload error: %v", err)
+	}
+	if encrypted.Alg != model.PayloadAlgAesGcm || encrypted.Timestamp == "" {
+		t.Fatalf("expected alg and timestamp, got %+v", encrypted)
+	}
+
+	cipherText, _ := base64.StdEncoding.DecodeString(encrypted.EncryptedData)
+	nonce, _ := base64.StdEncoding.DecodeString(encrypted.IV)
+	plainText, err := aesGcmDecrypt(cipherText, key, nonce, gcmAssociatedData(encrypted.Timestamp, "request-nonce"))
+	if err != nil {
+		t.Fatalf("aesGcmDecrypt error: %v", err)
+	}
+	var accepted model.JobAccepted
+	json.Unmarshal(plainText, &accepted)
+	if accepted.JobId != "abc" {
+		t.Fatalf("unexpected payload %s", plainText)
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// backend별로 connection string에 넣을 수 있는 옵션.
+// 여기에 없는 옵션(env_auth, shared_credentials_file 등)은 사용자 입력으로 설정할 수 없다.
+var backendOptions = map[string][]string{
+	"s3": {"access_key_id", "secret_access_key", "endpoint",
+		"provider", "region", "force_path_style", "v2_auth", "location_constraint", "acl",
+		"chunk_size", "upload_concurrency"},
+	"azureblob": {"account", "key", "sas_url"},
+	"gcs":       {"service_account_credentials", "project_number"},
+	"swift":     {"auth", "user", "key", "tenant", "domain", "region", "auth_version"},
+	"sftp":      {"host", "user", "port", "pass", "key_pem", "known_hosts_file"},
+	"local":     {},
+}
+
+type fsOption struct {
+	key   string
+	value string
+}
+
+// rclone connection string(":backend,key="value":path")을 만든다.
+// 값은 모두 큰따옴표로 감싸고 값 안의 큰따옴표는 두 번 써서 escape 한다.
+type fsBuilder struct {
+	backend string
+	allowed map[string]bool
+	options []fsOption
+	err     error
+}
+
+func newFsBuilder(backend string) (*fsBuilder, error) {
+	keys, ok := backendOptions[backend]
+	if !ok {
+		return nil, fmt.Errorf("unsupported backend %q", backend)
+	}
+	allowed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		allowed[key] = true
+	}
+	return &fsBuilder{backend: backend, allowed: allowed}, nil
+}
+
+// 옵션을 추가한다. 빈 값은 rclone 기본값을 쓰도록 생략한다.
+func (b *fsBuilder) set(key string, value string) {
+	if b.err != nil || value == "" {
+		return
+	}
+	if !b.allowed[key] {
+		b.err = fmt.Errorf("option %q is not allowed for %s", key, b.backend)
+		return
+	}
+	for _, option := range b.options {
+		if option.key == key {
+			b.err = fmt.Errorf("option %q is set more than once", key)
+			return
+		}
+	}
+	if err := checkEncodable(value); err != nil {
+		b.err = fmt.Errorf("option %q: %w", key, err)
+		return
+	}
+	b.options = append(b.options, fsOption{key, value})
+}
+
+func (b *fsBuilder) build(path string) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if err := checkEncodable(path); err != nil {
+		return "", fmt.Errorf("path: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(":" + b.backend)
+	for _, option := range b.options {
+		sb.WriteString("," + option.key + "=\"" + strings.ReplaceAll(option.value, "\"", "\"\"") + "\"")
+	}
+	sb.WriteString(":" + path)
+	fsString := sb.String()
+
+	// rclone이 같은 값으로 다시 읽을 수 있는지 확인한다.
+	parsed, err := fspath.Parse(fsString)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode connection string: %w", err)
+	}
+	if parsed.Name != ":"+b.backend || parsed.Path != path || len(parsed.Config) != len(b.options) {
+		return "", errors.New("cannot encode connection string")
+	}
+	for _, option := range b.options {
+		if parsed.Config[option.key] != option.value {
+			return "", fmt.Errorf("cannot encode option %q", option.key)
+		}
+	}
+	return fsString, nil
+}
+
+// 제어 문자와 잘못된 UTF-8은 connection string과 rclone 로그를 깨뜨리므로 거부한다.
+func checkEncodable(value string) error {
+	if !utf8.ValidString(value) {
+		return errors.New("value is not valid UTF-8")
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return errors.New("value contains control characters")
+		}
+	}
+	return nil
+}
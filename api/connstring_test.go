@@ -0,0 +1,102 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+func TestFsBuilder_HostileValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]string
+		path    string
+	}{
+		{"comma injects option", map[string]string{"secret_access_key": "abc,env_auth=true"}, "bucket"},
+		{"quote breaks out", map[string]string{"endpoint": `http://x",provider="Other`}, "bucket"},
+		{"doubled quotes", map[string]string{"secret_access_key": `a""b"`}, ""},
+		{"single quote", map[string]string{"access_key_id": `it's`}, ""},
+		{"colon ends params", map[string]string{"secret_access_key": "abc:/etc/passwd"}, "bucket"},
+		{"equals sign", map[string]string{"access_key_id": "a=b=c"}, ""},
+		{"path with separators", map[string]string{"access_key_id": "ak"}, `bucket,env_auth=true:"x"`},
+		{"unicode", map[string]string{"secret_access_key": "비밀번호✓"}, "버킷"},
+	}
+	for _, tt := range tests {
+		builder, err := newFsBuilder("s3")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for key, value := range tt.options {
+			builder.set(key, value)
+		}
+		got, err := builder.build(tt.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		parsed, err := fspath.Parse(got)
+		if err != nil {
+			t.Errorf("%s: rclone could not parse %s: %v", tt.name, got, err)
+			continue
+		}
+		if parsed.Name != ":s3" || parsed.Path != tt.path {
+			t.Errorf("%s: expected :s3 with path %q, got %q %q", tt.name, tt.path, parsed.Name, parsed.Path)
+		}
+		if len(parsed.Config) != len(tt.options) {
+			t.Errorf("%s: expected options %v, got %v", tt.name, tt.options, parsed.Config)
+		}
+		for key, value := range tt.options {
+			if parsed.Config[key] != value {
+				t.Errorf("%s: expected %s=%q, got %q", tt.name, key, value, parsed.Config[key])
+			}
+		}
+	}
+}
+
+func TestFsBuilder_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		options [][2]string
+		path    string
+	}{
+		{"unknown backend", "ftp", nil, ""},
+		{"backend injection", "s3,env_auth=true", nil, ""},
+		{"option not allowed", "s3", [][2]string{{"env_auth", "true"}}, ""},
+		{"credentials file not allowed", "s3", [][2]string{{"shared_credentials_file", "/etc/passwd"}}, ""},
+		{"option of other backend", "s3", [][2]string{{"sas_url", "https://x"}}, ""},
+		{"duplicate option", "s3", [][2]string{{"access_key_id", "a"}, {"access_key_id", "b"}}, ""},
+		{"newline in value", "s3", [][2]string{{"secret_access_key", "abc\nenv_auth=true"}}, ""},
+		{"nul in value", "s3", [][2]string{{"secret_access_key", "abc\x00"}}, ""},
+		{"invalid utf-8", "s3", [][2]string{{"secret_access_key", "\xff\xfe"}}, ""},
+		{"control char in path", "s3", nil, "bucket\r\n"},
+		{"local options", "local", [][2]string{{"copy_links", "true"}}, "/data"},
+	}
+	for _, tt := range tests {
+		builder, err := newFsBuilder(tt.backend)
+		if err != nil {
+			continue
+		}
+		for _, option := range tt.options {
+			builder.set(option[0], option[1])
+		}
+		if got, err := builder.build(tt.path); err == nil {
+			t.Errorf("%s: expected error, got %s", tt.name, got)
+		}
+	}
+}
+
+func TestFsBuilder_SkipsEmptyValues(t *testing.T) {
+	builder, _ := newFsBuilder("s3")
+	builder.set("access_key_id", "")
+	builder.set("endpoint", "http://minio:9000")
+
+	got, err := builder.build("")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got != `:s3,endpoint="http://minio:9000":` {
+		t.Fatalf("unexpected connection string %s", got)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kps-migration-api/model"
+	"net/http"
+	"runtime/debug"
+	gosync "sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
+	rcjobs "github.com/rclone/rclone/fs/rc/jobs"
+)
+
+// dry run 결과에 담는 작업별 최대 객체 수. 개수와 용량은 전체를 집계한다.
+var dryRunMaxObjects = 10000
+
+var rcloneRPCWithLogger = defaultRcloneRPCWithLogger
+
+// librclone.RPC와 같은 방식으로 rc 메소드를 호출하되,
+// rclone sync가 파일마다 판단한 결과를 logger로 전달받는다.
+func defaultRcloneRPCWithLogger(method string, input string, logger operations.LoggerFn) (result string, status int) {
+	defer func(start time.Time) { observeRcloneRPC(method, status, start) }(time.Now())
+
+	in := make(rc.Params)
+
+	// librclone.RPC처럼 rc 메소드의 panic은 500 결과로 돌려준다.
+	defer func() {
+		if r := recover(); r != nil {
+			result, status = rcloneError(method, in, fmt.Errorf("panic: %v\n%s", r, debug.Stack()), http.StatusInternalServerError)
+		}
+	}()
+
+	if err := json.Unmarshal([]byte(input), &in); err != nil {
+		return rcloneError(method, in, err, http.StatusBadRequest)
+	}
+
+	call := rc.Calls.Get(method)
+	if call == nil {
+		return rcloneError(method, in, errors.New("couldn't find method "+method), http.StatusNotFound)
+	}
+	if call.NeedsRequest || call.NeedsResponse {
+		return rcloneError(method, in, fmt.Errorf("method %q needs request or response, not supported", method), http.StatusNotFound)
+	}
+
+	// _async 호출은 새 context에서 실행되므로 logger는 호출 함수 안에서 넣는다.
+	fn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		return call.Fn(operations.WithLogger(ctx, logger), in)
+	}
+	_, out, err := rcjobs.NewJob(context.Background(), fn, in)
+	if err != nil {
+		return rcloneError(method, in, err, http.StatusInternalServerError)
+	}
+	if out == nil {
+		out = make(rc.Params)
+	}
+
+	byt, err := json.Marshal(out)
+	if err != nil {
+		return rcloneError(method, in, err, http.StatusInternalServerError)
+	}
+	return string(byt), http.StatusOK
+}
+
+func rcloneError(method string, in rc.Params, err error, status int) (string, int) {
+	params, status := rc.Error(method, in, err, status)
+	byt, _ := json.Marshal(params)
+	return string(byt), status
+}
+
+// rclone sync logger 결과를 copy, update, delete 목록으로 모은다.
+type dryRunCollector struct {
+	mu      gosync.Mutex
+	deletes bool
+	report  model.DryRunReport
+}
+
+func newDryRunCollector(operation string) *dryRunCollector {
+	return &dryRunCollector{deletes: operation == "sync"}
+}
+
+func (c *dryRunCollector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.report = model.DryRunReport{}
+}
+
+func (c *dryRunCollector) log(ctx context.Context, sigil operations.Sigil, src, dst fs.DirEntry, err error) {
+	if err != nil {
+		// 디렉토리와 오류는 객체 목록에 포함하지 않는다.
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch sigil {
+	case operations.MissingOnDst:
+		addPlannedObject(&c.report.Copy, src)
+	case operations.Differ:
+		addPlannedObject(&c.report.Update, src)
+	case operations.MissingOnSrc:
+		// copy는 대상에만 있는 객체를 삭제하지 않는다.
+		if c.deletes {
+			addPlannedObject(&c.report.Delete, dst)
+		}
+	}
+}
+
+func (c *dryRunCollector) snapshot() *model.DryRunReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report := c.report
+	return &report
+}
+
+func addPlannedObject(changes *model.PlannedChanges, entry fs.DirEntry) {
+	if entry == nil {
+		return
+	}
+	size := entry.Size()
+	changes.Count++
+	if size > 0 {
+		changes.Bytes += size
+	}
+	if len(changes.Objects) >= dryRunMaxObjects {
+		changes.Truncated = true
+		return
+	}
+	changes.Objects = append(changes.Objects, model.PlannedObject{Path: entry.Remote(), Size: size})
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+type fakeEntry struct {
+	remote string
+	size   int64
+}
+
+func (e fakeEntry) Fs() fs.Info                           { return nil }
+func (e fakeEntry) String() string                        { return e.remote }
+func (e fakeEntry) Remote() string                        { return e.remote }
+func (e fakeEntry) ModTime(ctx context.Context) time.Time { return time.Time{} }
+func (e fakeEntry) Size() int64                           { return e.size }
+
+func TestDryRunCollector_Sync(t *testing.T) {
+	c := newDryRunCollector("sync")
+	ctx := context.Background()
+
+	c.log(ctx, operations.MissingOnDst, fakeEntry{"new.txt", 10}, nil, nil)
+	c.log(ctx, operations.MissingOnDst, fakeEntry{"dir", 0}, nil, fs.ErrorIsDir)
+	c.log(ctx, operations.Differ, fakeEntry{"changed.txt", 20}, fakeEntry{"changed.txt", 5}, nil)
+	c.log(ctx, operations.Match, fakeEntry{"same.txt", 30}, fakeEntry{"same.txt", 30}, nil)
+	c.log(ctx, operations.MissingOnSrc, nil, fakeEntry{"old.txt", 40}, nil)
+
+	report := c.snapshot()
+	if report.Copy.Count != 1 || report.Copy.Bytes != 10 || report.Copy.Objects[0].Path != "new.txt" {
+		t.Fatalf("unexpected copy plan: %+v", report.Copy)
+	}
+	if report.Update.Count != 1 || report.Update.Bytes != 20 {
+		t.Fatalf("unexpected update plan: %+v", report.Update)
+	}
+	if report.Delete.Count != 1 || report.Delete.Bytes != 40 || report.Delete.Objects[0].Path != "old.txt" {
+		t.Fatalf("unexpected delete plan: %+v", report.Delete)
+	}
+}
+
+func TestDryRunCollector_CopyNeverDeletes(t *testing.T) {
+	c := newDryRunCollector("copy")
+	c.log(context.Background(), operations.MissingOnSrc, nil, fakeEntry{"old.txt", 40}, nil)
+
+	if report := c.snapshot(); report.Delete.Count != 0 {
+		t.Fatalf("expected no deletes for copy, got %+v", report.Delete)
+	}
+}
+
+func TestDryRunCollector_Truncates(t *testing.T) {
+	old := dryRunMaxObjects
+	defer func() { dryRunMaxObjects = old }()
+	dryRunMaxObjects = 1
+
+	c := newDryRunCollector("copy")
+	c.log(context.Background(), operations.MissingOnDst, fakeEntry{"a", 1}, nil, nil)
+	c.log(context.Background(), operations.MissingOnDst, fakeEntry{"b", 2}, nil, nil)
+
+	report := c.snapshot()
+	if report.Copy.Count != 2 || report.Copy.Bytes != 3 || len(report.Copy.Objects) != 1 || !report.Copy.Truncated {
+		t.Fatalf("expected truncated list with full totals, got %+v", report.Copy)
+	}
+}
+
+func TestDefaultRcloneRPCWithLogger_MatchesLibrclone(t *testing.T) {
+	rc.Add(rc.Call{
+		Path: "test/dryrunPanic",
+		Fn: func(ctx context.Context, in rc.Params) (rc.Params, error) {
+			panic("boom")
+		},
+	})
+	rc.Add(rc.Call{
+		Path:         "test/dryrunNeedsRequest",
+		Fn:           func(ctx context.Context, in rc.Params) (rc.Params, error) { return nil, nil },
+		NeedsRequest: true,
+	})
+
+	if _, status := defaultRcloneRPCWithLogger("test/dryrunPanic", "{}", nil); status != http.StatusInternalServerError {
+		t.Fatalf("expected panic to return 500, got %d", status)
+	}
+	if _, status := defaultRcloneRPCWithLogger("test/dryrunNeedsRequest", "{}", nil); status != http.StatusNotFound {
+		t.Fatalf("expected request-bound method to be rejected with 404, got %d", status)
+	}
+}
+
+func TestSync_DryRun_UsesRcloneConfigAndCollectsPlan(t *testing.T) {
+	if config.Env != nil {
+		config.Env.IsEncryption = "false"
+	}
+
+	oldRPC := rcloneRPCWithLogger
+	defer func() { rcloneRPCWithLogger = oldRPC }()
+
+	var lastPayload string
+	rcloneRPCWithLogger = func(method string, input string, logger operations.LoggerFn) (string, int) {
+		lastPayload = input
+		logger(context.Background(), operations.MissingOnDst, fakeEntry{"new.txt", 10}, nil, nil)
+		return `{"jobid":1}`, 200
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, _ *string, setStatus func(int), setOut func(string)) {
+		body, _ := json.Marshal(model.SyncConfig{
+			Src:    model.StorageConfig{StorageType: "s3", Endpoint: "http://src"},
+			Dst:    model.StorageConfig{StorageType: "s3", Endpoint: "http://dst"},
+			DryRun: true,
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		sync(w, req)
+
+		if w.Result().StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", w.Result().StatusCode)
+		}
+
+		var syncRequest model.SyncRequest
+		if err := json.Unmarshal([]byte(lastPayload), &syncRequest); err != nil {
+			t.Fatalf("unmarshal RPC payload: %v", err)
+		}
+		if syncRequest.Config == nil || !syncRequest.Config.DryRun {
+			t.Fatalf("expected _config.DryRun in RPC payload, got %s", lastPayload)
+		}
+
+		var accepted model.JobAccepted
+		decodeResponse(t, w.Result().Body, &accepted)
+		j, ok := jobs.get(accepted.JobId)
+		if !ok {
+			t.Fatalf("expected job %q to be registered", accepted.JobId)
+		}
+		status := j.snapshot()
+		if !status.DryRun || status.Plan == nil || status.Plan.Copy.Count != 1 {
+			t.Fatalf("expected dry run plan in job status, got %+v", status)
+		}
+	})
+}
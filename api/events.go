@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 진행 상황 이벤트 전송 주기
+var jobEventInterval = time.Second
+
+func writeEvent(wr io.Writer, event string, data interface{}) error {
+	byt, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(wr, "event: %s\ndata: %s\n\n", event, byt)
+	return err
+}
+
+// @Summary Stream migration job progress
+// @Description Stream progress snapshots of a migration job as Server-Sent Events.
+// @Description A "progress" event is sent periodically with rclone core/stats of the job.
+// @Description A "done" event with the final job status is sent when the job ends, then the stream is closed.
+// @Tags Migration
+// @Produce text/event-stream
+// @Param id path string true "job id"
+// @Success 200 {object} model.JobProgress
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/jobs/{id}/events [get]
+func jobEvents(wr http.ResponseWriter, r *http.Request) {
+	j, ok := jobs.get(mux.Vars(r)["id"])
+	if !ok {
+		writeJobNotFound(wr)
+		return
+	}
+	if err := authorizeJob(r, j); err != nil {
+		writeForbidden(wr, err)
+		return
+	}
+
+	// 스트림은 서버의 WriteTimeout보다 오래 유지된다.
+	rc := http.NewResponseController(wr)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	wr.Header().Set("Content-Type", "text/event-stream")
+	wr.Header().Set("Cache-Control", "no-cache")
+	// nginx ingress의 응답 버퍼링 해제
+	wr.Header().Set("X-Accel-Buffering", "no")
+	wr.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(jobEventInterval)
+	defer ticker.Stop()
+
+	for {
+		if progress, err := j.progress(); err == nil {
+			if err := writeEvent(wr, "progress", progress); err != nil {
+				return
+			}
+		}
+
+		status := j.snapshot()
+		if status.State.IsFinal() {
+			writeEvent(wr, "done", status)
+			rc.Flush()
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
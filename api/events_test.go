@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kps-migration-api/model"
+
+	"github.com/gorilla/mux"
+)
+
+func TestJobEvents_NotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/unknown/events", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "unknown"})
+	w := httptest.NewRecorder()
+
+	jobEvents(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestJobEvents_FinishedJobSendsProgressAndDone(t *testing.T) {
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(jobs, "copy", "sync/copy", model.SyncRequest{})
+		defer jobs.remove(j.id)
+		j.finish(0, model.RcloneJobStatus{Success: true, EndTime: time.Now()})
+
+		setOut(`{"bytes":1024,"totalBytes":2048,"speed":512,"eta":2,"checks":3,"errors":0,"transferring":[{"name":"a.txt","size":2048,"bytes":1024,"percentage":50}]}`)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/"+j.id+"/events", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": j.id})
+		w := httptest.NewRecorder()
+
+		jobEvents(w, req)
+
+		if *lastMethod != "core/stats" {
+			t.Fatalf("expected method core/stats, got %q", *lastMethod)
+		}
+		if !strings.Contains(*lastPayload, j.group) {
+			t.Fatalf("expected stats request for group %q, got %s", j.group, *lastPayload)
+		}
+		if ct := w.Result().Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected text/event-stream, got %q", ct)
+		}
+		// hop-by-hop 헤더는 보내지 않는다. HTTP/2에서는 잘못된 응답이 된다.
+		if h := w.Result().Header; h.Get("Connection") != "" || h.Get("Cache-Control") != "no-cache" || h.Get("X-Accel-Buffering") != "no" {
+			t.Fatalf("unexpected stream headers: %v", h)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "event: progress\ndata: ") || !strings.Contains(body, `"totalBytes":2048`) {
+			t.Fatalf("expected progress event, got %q", body)
+		}
+		if !strings.Contains(body, "event: done\ndata: ") {
+			t.Fatalf("expected done event, got %q", body)
+		}
+	})
+}
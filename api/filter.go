@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"kps-migration-api/model"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+)
+
+// 요청의 filter를 rclone _filter 파라미터로 변환한다.
+// 잘못된 glob, 규칙, 크기, 기간은 rclone 작업을 시작하기 전에 오류로 반환한다.
+func buildRcloneFilter(config *model.FilterConfig) (*model.RcloneFilter, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	rcloneFilter := &model.RcloneFilter{
+		IncludeRule: config.Include,
+		ExcludeRule: config.Exclude,
+		FilterRule:  config.Rules,
+		MinSize:     config.MinSize,
+		MaxSize:     config.MaxSize,
+		MinAge:      config.MinAge,
+		MaxAge:      config.MaxAge,
+	}
+
+	// rclone이 _filter를 읽는 것과 같은 방식으로 옵션을 만들어 검증한다.
+	opt := filter.Options{
+		MinAge:  fs.DurationOff,
+		MaxAge:  fs.DurationOff,
+		MinSize: fs.SizeSuffix(-1),
+		MaxSize: fs.SizeSuffix(-1),
+	}
+	byt, err := json.Marshal(rcloneFilter)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(byt, &opt); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if _, err := filter.NewFilter(&opt); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return rcloneFilter, nil
+}
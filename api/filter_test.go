@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+func TestBuildRcloneFilter_Nil(t *testing.T) {
+	f, err := buildRcloneFilter(nil)
+	if err != nil || f != nil {
+		t.Fatalf("expected nil filter, got %+v (err=%v)", f, err)
+	}
+}
+
+func TestBuildRcloneFilter_Valid(t *testing.T) {
+	f, err := buildRcloneFilter(&model.FilterConfig{
+		Exclude: []string{"*.log", "tmp/**"},
+		Rules:   []string{"- *.bak", "+ data/**"},
+		MinSize: "1k",
+		MaxSize: "10G",
+		MinAge:  "1h",
+		MaxAge:  "30d",
+	})
+	if err != nil {
+		t.Fatalf("expected valid filter, got %v", err)
+	}
+	if len(f.ExcludeRule) != 2 || len(f.FilterRule) != 2 || f.MaxAge != "30d" {
+		t.Fatalf("unexpected rclone filter: %+v", f)
+	}
+}
+
+func TestBuildRcloneFilter_Invalid(t *testing.T) {
+	tests := map[string]model.FilterConfig{
+		"bad glob": {Include: []string{"data/{a,b"}},
+		"bad rule": {Rules: []string{"x *.log"}},
+		"bad size": {MinSize: "10Q"},
+		"bad age":  {MaxAge: "yesterday"},
+	}
+	for name, cfg := range tests {
+		cfg := cfg
+		if _, err := buildRcloneFilter(&cfg); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestCopy_InvalidFilter_Returns400(t *testing.T) {
+	if config.Env != nil {
+		config.Env.IsEncryption = "false"
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		body, _ := json.Marshal(model.SyncConfig{
+			Src:    model.StorageConfig{StorageType: "s3", Endpoint: "http://src"},
+			Dst:    model.StorageConfig{StorageType: "s3", Endpoint: "http://dst"},
+			Filter: &model.FilterConfig{Include: []string{"{unclosed"}},
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/copy", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		copy(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", w.Result().StatusCode)
+		}
+		if *lastMethod != "" {
+			t.Fatalf("expected no rclone call, got %q", *lastMethod)
+		}
+	})
+}
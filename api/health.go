@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"time"
+)
+
+const (
+	// job 저장소 lock을 기다리는 시간
+	jobStoreLockTimeout = time.Second
+	// JobCheckpointFile 디렉토리에 쓸 수 있는지 다시 확인하는 간격
+	checkpointDirCheckInterval = time.Minute
+)
+
+type healthCheck struct {
+	name  string
+	check func() error
+	// /actuator/health에 함께 보여줄 값. 없으면 nil
+	details func() map[string]interface{}
+}
+
+// readiness 확인 항목. /actuator/health는 liveness와 함께 각 항목의 결과를 보여준다.
+var readinessChecks = []healthCheck{
+	{"shutdown", checkShutdown, nil},
+	{"encryptionKey", checkEncryptionKey, nil},
+	{"jobStore", checkJobStore, nil},
+	{"activeJobs", checkActiveJobs, activeJobsDetails},
+}
+
+func isReady() error {
+	if err := isAlive(); err != nil {
+		return err
+	}
+	for _, c := range readinessChecks {
+		if err := c.check(); err != nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+func checkShutdown() error {
+	if draining.Load() {
+		return errShuttingDown
+	}
+	return nil
+}
+
+// 암호화를 사용하면 복호화할 수 있는 RSA 키가 하나 이상 있어야 한다.
+func checkEncryptionKey() error {
+	if config.Env.IsEncryption != "true" {
+		return nil
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return fmt.Errorf("PrivateKeyDir: %w", err)
+	}
+	if config.Env.PrivateKey != "" {
+		if _, err := parsePrivateKey([]byte(config.Env.PrivateKey)); err != nil {
+			return fmt.Errorf("PrivateKey: %w", err)
+		}
+		return nil
+	}
+	if len(ring.activeKeys(time.Now())) == 0 {
+		return errors.New("no active private key")
+	}
+	return nil
+}
+
+// job을 등록할 수 있어야 한다. JobCheckpointFile이 있으면 그 디렉토리에 쓸 수 있고 암호화할 RSA 키가 있어야 한다.
+func checkJobStore() error {
+	// probe마다 goroutine을 남기지 않도록 TryRLock으로 확인한다.
+	deadline := time.Now().Add(jobStoreLockTimeout)
+	for !jobs.mu.TryRLock() {
+		if time.Now().After(deadline) {
+			return errors.New("job store is locked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	jobs.mu.RUnlock()
+
+	if config.Env.JobCheckpointFile == "" {
+		return nil
+	}
+	if _, _, err := checkpointKey(); err != nil {
+		return fmt.Errorf("JobCheckpointFile: %w", err)
+	}
+	if err := checkpointDir.check(filepath.Dir(config.Env.JobCheckpointFile)); err != nil {
+		return fmt.Errorf("JobCheckpointFile: %w", err)
+	}
+	return nil
+}
+
+// 디렉토리에 파일을 만들 수 있는지 확인한 결과. checkpointDirCheckInterval 동안 다시 확인하지 않는다.
+type dirCheck struct {
+	mu        gosync.Mutex
+	dir       string
+	checkedAt time.Time
+	err       error
+}
+
+var checkpointDir = &dirCheck{}
+
+func (c *dirCheck) check(dir string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if dir == c.dir && time.Since(c.checkedAt) < checkpointDirCheckInterval {
+		return c.err
+	}
+	c.dir, c.checkedAt = dir, time.Now()
+	c.err = writable(dir)
+	return c.err
+}
+
+func writable(dir string) error {
+	file, err := os.CreateTemp(dir, ".health-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func isActive(_ *job, state model.JobState) bool {
+	return state == model.JobStateRunning || state == model.JobStateQueued
+}
+
+// 실행 중이거나 대기 중인 job이 ReadinessMaxActiveJobs 이상이면(대기열이 가득 차면) 새 요청을 받지 않는다.
+func checkActiveJobs() error {
+	max := config.Env.ReadinessMaxActiveJobs
+	if max <= 0 {
+		return nil
+	}
+	if active := countJobs(nil, isActive); active >= max {
+		return fmt.Errorf("%d jobs are running or queued (max %d)", active, max)
+	}
+	return nil
+}
+
+func activeJobsDetails() map[string]interface{} {
+	isQueued := func(_ *job, state model.JobState) bool { return state == model.JobStateQueued }
+	return map[string]interface{}{
+		"running":                countJobs(nil, isRunning),
+		"queued":                 countJobs(nil, isQueued),
+		"maxConcurrentJobs":      config.Env.MaxConcurrentJobs,
+		"maxQueuedJobs":          config.Env.MaxQueuedJobs,
+		"readinessMaxActiveJobs": config.Env.ReadinessMaxActiveJobs,
+	}
+}
+
+// @Summary Report health checks
+// @Description Report liveness and each readiness check (shutdown, encryptionKey, jobStore, activeJobs).
+// @Description Returns 503 when any check is DOWN. "activeJobs" reports running and queued jobs in "details" and is DOWN when the job queue is full.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} model.HealthReport
+// @Failure 503 {object} model.HealthReport
+// @Router /actuator/health [get]
+func health(wr http.ResponseWriter, r *http.Request) {
+	report := model.HealthReport{Status: model.HealthStatusUp}
+	checks := append([]healthCheck{{"alive", isAlive, nil}}, readinessChecks...)
+	for _, c := range checks {
+		result := model.HealthCheck{Name: c.name, Status: model.HealthStatusUp}
+		if err := c.check(); err != nil {
+			result.Status = model.HealthStatusDown
+			result.Error = err.Error()
+			report.Status = model.HealthStatusDown
+		}
+		if c.details != nil {
+			result.Details = c.details()
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	status := http.StatusOK
+	if report.Status != model.HealthStatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	// probe와 같이 응답 envelope을 사용하지 않는다.
+	wr.Header().Set("Content-type", "application/json")
+	wr.Header().Set("Cache-Control", "no-store")
+	wr.WriteHeader(status)
+	json.NewEncoder(wr).Encode(report)
+}
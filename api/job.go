@@ -0,0 +1,710 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"kps-migration-api/model"
+	"net/http"
+	gosync "sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// rclone job/status 조회 주기
+	jobPollInterval = time.Second
+	// 종료된 job을 보관하는 기간
+	jobRetention = 24 * time.Hour
+)
+
+type job struct {
+	id          string
+	mu          gosync.Mutex
+	status      model.JobStatus
+	method      string
+	request     model.SyncRequest
+	rcloneJobId int64
+	group       string
+	plan        *dryRunCollector
+	auditRecord *model.AuditRecord
+	// 종료 처리(집계, 감사 로그)를 했는지 여부와 종료 시점의 전송량
+	endHandled    bool
+	finalProgress *model.JobProgress
+	// 재개 전 실행들의 전송량. rclone stats는 재개할 때 지운다.
+	previous model.JobTransferTotals
+	// job span과 그 context (tracing.go)
+	traceCtx context.Context
+	span     trace.Span
+}
+
+type jobStore struct {
+	mu   gosync.RWMutex
+	jobs map[string]*job
+}
+
+var jobs = newJobStore()
+
+var errJobNotActive = errors.New("job is not active")
+
+// job의 백그라운드 작업(rclone 상태 조회, 대기열에서 꺼낸 job 실행)
+var jobRoutines gosync.WaitGroup
+
+func goJob(fn func()) {
+	jobRoutines.Add(1)
+	go func() {
+		defer jobRoutines.Done()
+		fn()
+	}()
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]*job)}
+}
+
+func newJobId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// 새 job을 pending으로 등록한다. scheduler.admit이 실행할지 대기할지 정한다.
+// rclone 작업은 group 이름으로 job과 연결된다.
+func (s *jobStore) create(operation string, method string, request model.SyncRequest) *job {
+	id := newJobId()
+	request.Async = true
+	request.Group = "job/" + id
+	j := &job{
+		id: id,
+		status: model.JobStatus{
+			JobId:     id,
+			Operation: operation,
+			State:     model.JobStatePending,
+			StartTime: time.Now().UTC(),
+		},
+		method:  method,
+		request: request,
+		group:   request.Group,
+	}
+	if request.Config != nil && request.Config.DryRun {
+		j.status.DryRun = true
+		j.plan = newDryRunCollector(operation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.jobs[id] = j
+	return j
+}
+
+// checkpoint의 job을 같은 id로 다시 등록한다.
+func (s *jobStore) restore(checkpoint model.JobCheckpoint) *job {
+	j := &job{
+		id:          checkpoint.Status.JobId,
+		status:      checkpoint.Status,
+		method:      checkpoint.Method,
+		request:     checkpoint.Request,
+		group:       checkpoint.Request.Group,
+		auditRecord: checkpoint.AuditRecord,
+		previous:    checkpoint.Transferred,
+	}
+	if checkpoint.Status.DryRun {
+		j.plan = newDryRunCollector(checkpoint.Status.Operation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[j.id] = j
+	return j
+}
+
+func (s *jobStore) get(id string) (*job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+func (s *jobStore) list() []*job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		list = append(list, j)
+	}
+	return list
+}
+
+func (s *jobStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+}
+
+func (s *jobStore) pruneLocked() {
+	deadline := time.Now().Add(-jobRetention)
+	for id, j := range s.jobs {
+		snapshot := j.snapshot()
+		if snapshot.EndTime != nil && snapshot.EndTime.Before(deadline) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// job을 요청한 사용자 (token subject)
+func (j *job) setOwner(owner string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Owner = owner
+}
+
+func (j *job) owner() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.Owner
+}
+
+// 대기열 우선순위 (scheduler.go). 대기열에 넣기 전에 정한다.
+func (j *job) setPriority(priority string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Priority = priority
+}
+
+func (j *job) state() model.JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.State
+}
+
+func (j *job) snapshot() model.JobStatus {
+	j.mu.Lock()
+	status := j.status
+	j.mu.Unlock()
+
+	if j.plan != nil {
+		status.Plan = j.plan.snapshot()
+	}
+	return status
+}
+
+// 응답에 사용하는 상태. 대기 중이면 대기열에서의 순서를 포함한다.
+func (j *job) report() model.JobStatus {
+	status := j.snapshot()
+	if status.State == model.JobStateQueued {
+		status.QueuePosition = scheduler.position(j)
+	}
+	return status
+}
+
+// rclone 작업을 비동기로 시작하고 상태 조회를 시작한다.
+// 실패하면 응답에 사용할 http status를 함께 반환한다.
+func (j *job) run() (int, error) {
+	requestJSON, err := json.Marshal(j.request)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	j.mu.Lock()
+	previous := j.rcloneJobId
+	j.mu.Unlock()
+	if previous != 0 {
+		j.carryOverStats()
+	}
+
+	var out string
+	var status int
+	if j.plan != nil {
+		// dry run은 rclone의 판단 결과를 수집해 변경 예정 목록을 만든다.
+		j.plan.reset()
+		out, status = traceRclone(j.traceContext(), j.method, func() (string, int) {
+			return rcloneRPCWithLogger(j.method, string(requestJSON), j.plan.log)
+		})
+	} else {
+		out, status = callRclone(j.traceContext(), j.method, string(requestJSON))
+	}
+	if status != http.StatusOK {
+		return status, errors.New(rcloneErrorMessage(out, status))
+	}
+
+	rcloneJobId, err := parseRcloneJobId(out)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	j.mu.Lock()
+	j.rcloneJobId = rcloneJobId
+	j.mu.Unlock()
+	logger.InfoContext(j.traceContext(), "migration job started", "job_id", j.id, "operation", j.status.Operation, "rclone_job_id", rcloneJobId)
+	goJob(func() { j.watch(rcloneJobId, jobPollInterval) })
+	return http.StatusOK, nil
+}
+
+// rclone 작업이 끝날 때까지 job/status를 주기적으로 조회한다.
+// 중지, 일시정지로 job이 더 이상 이 rclone 작업을 실행하지 않으면 결과를 반영하지 않으므로 조회를 멈춘다.
+func (j *job) watch(rcloneJobId int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !j.runs(rcloneJobId) || j.refresh(rcloneJobId) {
+			return
+		}
+	}
+}
+
+func (j *job) runs(rcloneJobId int64) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rcloneJobId == rcloneJobId && j.status.State == model.JobStateRunning
+}
+
+// rclone의 job 상태를 반영하고 작업이 끝났는지 여부를 반환한다.
+func (j *job) refresh(rcloneJobId int64) bool {
+	requestJSON, err := json.Marshal(model.RcloneJobRequest{JobId: rcloneJobId})
+	if err != nil {
+		j.finish(rcloneJobId, model.RcloneJobStatus{EndTime: time.Now().UTC(), Error: err.Error()})
+		return true
+	}
+
+	// 주기적인 polling은 span을 만들지 않는다. 상태 변화는 job span의 event로 남는다.
+	out, status := rcloneRPC("job/status", string(requestJSON))
+	if status != http.StatusOK {
+		j.finish(rcloneJobId, model.RcloneJobStatus{EndTime: time.Now().UTC(), Error: rcloneErrorMessage(out, status)})
+		return true
+	}
+
+	var rcloneStatus model.RcloneJobStatus
+	if err := json.Unmarshal([]byte(out), &rcloneStatus); err != nil {
+		j.finish(rcloneJobId, model.RcloneJobStatus{EndTime: time.Now().UTC(), Error: err.Error()})
+		return true
+	}
+	if !rcloneStatus.Finished {
+		return false
+	}
+	j.finish(rcloneJobId, rcloneStatus)
+	return true
+}
+
+// rclone 작업 결과를 반영한다. 이미 교체된 rclone 작업이거나
+// 사용자가 중지, 일시정지한 job이면 결과를 무시한다.
+func (j *job) finish(rcloneJobId int64, result model.RcloneJobStatus) {
+	defer j.onEnd()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if rcloneJobId != j.rcloneJobId || j.status.State != model.JobStateRunning {
+		return
+	}
+
+	endTime := result.EndTime.UTC()
+	j.status.EndTime = &endTime
+	j.status.Output = result.Output
+	if result.Success {
+		j.status.State = model.JobStateSuccess
+	} else {
+		j.status.State = model.JobStateError
+		j.status.Error = j.redact(result.Error)
+		_, j.status.ErrorCode = classifyRcloneError(result.Error, http.StatusInternalServerError)
+	}
+}
+
+// 오류 메시지에서 job의 connection string에 포함된 인증 정보를 가린다.
+func (j *job) redact(message string) string {
+	return redactSecrets(message, j.request.SrcFs, j.request.DstFs)
+}
+
+func (j *job) recordLocked(action string) {
+	j.status.Actions = append(j.status.Actions, model.JobAction{Action: action, Time: time.Now().UTC()})
+	if j.span != nil {
+		j.span.AddEvent(action)
+	}
+}
+
+// 대기열에서 꺼낸 job을 실행한다. 실패하면 job을 error로 끝낸다.
+func (j *job) start() {
+	defer j.onEnd()
+	if _, err := j.run(); err != nil {
+		j.fail(err)
+	}
+}
+
+// rclone 작업을 시작하지 못한 job을 error로 끝낸다. 그 사이 중지된 job은 그대로 둔다.
+func (j *job) fail(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.State != model.JobStateRunning {
+		return
+	}
+	endTime := time.Now().UTC()
+	j.status.State = model.JobStateError
+	j.status.EndTime = &endTime
+	j.status.Error = j.redact(err.Error())
+	_, j.status.ErrorCode = classifyRcloneError(err.Error(), http.StatusInternalServerError)
+}
+
+// job을 중지한다. 실행 중이면 rclone 작업도 중지한다.
+func (j *job) cancel() error {
+	defer j.onEnd()
+	j.mu.Lock()
+	state := j.status.State
+	rcloneJobId := j.rcloneJobId
+	if state != model.JobStateRunning && state != model.JobStateQueued && state != model.JobStatePaused && state != model.JobStateInterrupted {
+		j.mu.Unlock()
+		return errJobNotActive
+	}
+	endTime := time.Now().UTC()
+	j.status.State = model.JobStateCancelled
+	j.status.EndTime = &endTime
+	j.recordLocked(model.JobActionCancel)
+	j.mu.Unlock()
+
+	if state == model.JobStateRunning {
+		return stopRcloneJob(j.traceContext(), rcloneJobId)
+	}
+	return nil
+}
+
+// rclone의 대역폭 제한은 프로세스 전체에 적용되므로 job 단위로 멈출 수 없다.
+// 일시정지는 rclone 작업을 중지하고, 재개는 같은 요청으로 작업을 다시 시작한다.
+// 이미 대상에 있는 파일은 rclone이 건너뛰므로 남은 파일만 전송된다.
+func (j *job) pause() error {
+	j.mu.Lock()
+	if j.status.State != model.JobStateRunning {
+		j.mu.Unlock()
+		return errJobNotActive
+	}
+	rcloneJobId := j.rcloneJobId
+	j.status.State = model.JobStatePaused
+	j.recordLocked(model.JobActionPause)
+	j.mu.Unlock()
+
+	// 비워진 자리에 대기 중인 job을 실행한다.
+	defer scheduler.dispatch()
+	return stopRcloneJob(j.traceContext(), rcloneJobId)
+}
+
+// 일시정지했거나 서버 종료로 중지된 job을 재개한다. 실행할 자리가 없으면 대기열에 넣는다.
+func (j *job) resume() error {
+	defer j.onEnd()
+	start, err := scheduler.admit(j, model.JobActionResume)
+	if err != nil || !start {
+		return err
+	}
+
+	if _, err := j.run(); err != nil {
+		j.fail(err)
+		return err
+	}
+	return nil
+}
+
+// 서버 종료 시 실행 중이거나 대기 중인 job을 중지하고 interrupted로 기록한다.
+func (j *job) interrupt() error {
+	j.mu.Lock()
+	state := j.status.State
+	if state != model.JobStateRunning && state != model.JobStateQueued && state != model.JobStatePending {
+		j.mu.Unlock()
+		return errJobNotActive
+	}
+	rcloneJobId := j.rcloneJobId
+	j.status.State = model.JobStateInterrupted
+	j.recordLocked(model.JobActionInterrupt)
+	j.mu.Unlock()
+
+	if state == model.JobStateRunning {
+		return stopRcloneJob(j.traceContext(), rcloneJobId)
+	}
+	return nil
+}
+
+// 재개할 수 있는 job의 checkpoint
+func (j *job) checkpoint() (model.JobCheckpoint, bool) {
+	j.mu.Lock()
+	if !resumable(j.status.State) {
+		j.mu.Unlock()
+		return model.JobCheckpoint{}, false
+	}
+	checkpoint := model.JobCheckpoint{
+		Status:      j.status,
+		Method:      j.method,
+		Request:     j.request,
+		AuditRecord: j.auditRecord,
+		Transferred: j.previous,
+	}
+	ran := j.rcloneJobId != 0
+	j.mu.Unlock()
+
+	// 재시작하면 rclone stats가 사라지므로 마지막 실행의 전송량을 더해 저장한다.
+	if ran {
+		if stats, err := j.rcloneStats(); err == nil {
+			addTransferTotals(&checkpoint.Transferred, stats)
+		} else {
+			logger.Warn("failed to read job stats for checkpoint", "job_id", j.id, "error", err)
+		}
+	}
+	return checkpoint, true
+}
+
+func stopRcloneJob(ctx context.Context, rcloneJobId int64) error {
+	requestJSON, err := json.Marshal(model.RcloneJobRequest{JobId: rcloneJobId})
+	if err != nil {
+		return err
+	}
+
+	out, status := callRclone(ctx, "job/stop", string(requestJSON))
+	if status != http.StatusOK {
+		return errors.New(rcloneErrorMessage(out, status))
+	}
+	return nil
+}
+
+// 종료된 job의 전송량을 한 번만 집계하고 감사 로그를 남긴다.
+func (j *job) onEnd() {
+	j.mu.Lock()
+	if j.endHandled || !j.status.State.IsFinal() {
+		j.mu.Unlock()
+		return
+	}
+	j.endHandled = true
+	j.mu.Unlock()
+
+	status := j.snapshot()
+	progress, err := j.progress()
+	if err == nil {
+		j.mu.Lock()
+		j.finalProgress = &progress
+		j.mu.Unlock()
+	}
+	logger.InfoContext(j.traceContext(), "migration job finished", "job_id", j.id, "operation", status.Operation,
+		"state", status.State, "error_code", status.ErrorCode, "bytes", progress.Bytes)
+	observeJobEnd(status, progress)
+	j.endTrace(status, progress)
+	j.writeAudit(status, progress)
+	scheduler.dispatch()
+}
+
+// 종료된 job은 종료 시점의 전송량을, 실행 중인 job은 현재 rclone stats를 반환한다.
+func (j *job) transferStats() (model.JobProgress, bool) {
+	j.mu.Lock()
+	final, endHandled := j.finalProgress, j.endHandled
+	j.mu.Unlock()
+
+	if endHandled {
+		if final == nil {
+			return model.JobProgress{}, false
+		}
+		return *final, true
+	}
+	progress, err := j.progress()
+	return progress, err == nil
+}
+
+// job의 전송량. 현재 실행의 rclone stats에 재개 전 실행들의 전송량을 더한다.
+func (j *job) progress() (model.JobProgress, error) {
+	progress, err := j.rcloneStats()
+	if err != nil {
+		return progress, err
+	}
+
+	j.mu.Lock()
+	previous := j.previous
+	j.mu.Unlock()
+	progress.Bytes += previous.Bytes
+	progress.TotalBytes += previous.Bytes
+	progress.Checks += previous.Checks
+	progress.TotalChecks += previous.Checks
+	progress.Transfers += previous.Transfers
+	progress.TotalTransfers += previous.Transfers
+	progress.Deletes += previous.Deletes
+	progress.Errors += previous.Errors
+
+	snapshot := j.snapshot()
+	progress.JobId = snapshot.JobId
+	progress.State = snapshot.State
+	return progress, nil
+}
+
+// job의 stats group에 대한 rclone core/stats 결과를 조회한다.
+func (j *job) rcloneStats() (model.JobProgress, error) {
+	var progress model.JobProgress
+	requestJSON, err := json.Marshal(model.RcloneStatsRequest{Group: j.group})
+	if err != nil {
+		return progress, err
+	}
+
+	out, status := rcloneRPC("core/stats", string(requestJSON))
+	if status != http.StatusOK {
+		return progress, errors.New(rcloneErrorMessage(out, status))
+	}
+	err = json.Unmarshal([]byte(out), &progress)
+	return progress, err
+}
+
+// 재개할 때는 같은 group으로 다시 실행하므로 이전 실행의 전송량을 job에 옮기고 rclone stats를 지운다.
+// 지우지 못하면 rclone stats에 그대로 남으므로 옮기지 않는다.
+func (j *job) carryOverStats() {
+	stats, err := j.rcloneStats()
+	if err == nil {
+		err = j.resetStats()
+	}
+	if err != nil {
+		logger.WarnContext(j.traceContext(), "failed to reset job stats", "job_id", j.id, "error", err)
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	addTransferTotals(&j.previous, stats)
+}
+
+func addTransferTotals(totals *model.JobTransferTotals, stats model.JobProgress) {
+	totals.Bytes += stats.Bytes
+	totals.Checks += stats.Checks
+	totals.Transfers += stats.Transfers
+	totals.Deletes += stats.Deletes
+	totals.Errors += stats.Errors
+}
+
+func (j *job) resetStats() error {
+	requestJSON, err := json.Marshal(model.RcloneStatsRequest{Group: j.group})
+	if err != nil {
+		return err
+	}
+	out, status := rcloneRPC("core/stats-reset", string(requestJSON))
+	if status != http.StatusOK {
+		return errors.New(rcloneErrorMessage(out, status))
+	}
+	return nil
+}
+
+// rclone 비동기 호출 결과에서 jobid를 꺼낸다.
+func parseRcloneJobId(out string) (int64, error) {
+	var result struct {
+		JobId *int64 `json:"jobid"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return 0, err
+	}
+	if result.JobId == nil {
+		return 0, errors.New("rclone did not return a job id")
+	}
+	return *result.JobId, nil
+}
+
+// @Summary Check migration job status
+// @Description Check the state, start and end time and the final error or result of a migration job.
+// @Description A failed job also reports "errorCode" (AUTH_FAILED, BUCKET_NOT_FOUND, ENDPOINT_UNREACHABLE, ...).
+// @Description A queued job reports its "queuePosition" (1 runs next).
+// @Tags Migration
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} model.Response{data=model.JobStatus}
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/jobs/{id} [get]
+func jobStatus(wr http.ResponseWriter, r *http.Request) {
+	j, ok := jobs.get(mux.Vars(r)["id"])
+	if !ok {
+		writeJobNotFound(wr)
+		return
+	}
+	if err := authorizeJob(r, j); err != nil {
+		writeForbidden(wr, err)
+		return
+	}
+
+	writeData(wr, http.StatusOK, j.report())
+}
+
+// @Summary Cancel migration job
+// @Description Stop a running, queued or paused migration job.
+// @Tags Migration
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} model.Response{data=model.JobStatus}
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/jobs/{id} [delete]
+func cancelJob(wr http.ResponseWriter, r *http.Request) {
+	jobAction(wr, r, (*job).cancel)
+}
+
+// @Summary Pause migration job
+// @Description Pause a running migration job. Files already transferred are kept.
+// @Tags Migration
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} model.Response{data=model.JobStatus}
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/jobs/{id}/pause [post]
+func pauseJob(wr http.ResponseWriter, r *http.Request) {
+	jobAction(wr, r, (*job).pause)
+}
+
+// @Summary Resume migration job
+// @Description Resume a paused migration job, or a job interrupted by a server shutdown. Files already at the destination are skipped.
+// @Description The job is queued when no slot is free, and 429 with Retry-After is returned when the queue is full.
+// @Tags Migration
+// @Produce json
+// @Param id path string true "job id"
+// @Success 200 {object} model.Response{data=model.JobStatus}
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 429 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Failure 503 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/jobs/{id}/resume [post]
+func resumeJob(wr http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		writeShuttingDown(wr)
+		return
+	}
+	jobAction(wr, r, (*job).resume)
+}
+
+func jobAction(wr http.ResponseWriter, r *http.Request, action func(*job) error) {
+	j, ok := jobs.get(mux.Vars(r)["id"])
+	if !ok {
+		writeJobNotFound(wr)
+		return
+	}
+	if err := authorizeJob(r, j); err != nil {
+		writeForbidden(wr, err)
+		return
+	}
+
+	if err := action(j); err != nil {
+		if errors.Is(err, errJobNotActive) {
+			writeError(wr, http.StatusConflict, errCodeJobNotActive, err.Error(), map[string]interface{}{"state": j.snapshot().State})
+		} else if isJobLimit(err) {
+			writeJobLimit(wr, err)
+		} else {
+			status, code := classifyRcloneError(err.Error(), http.StatusInternalServerError)
+			writeError(wr, status, code, j.redact(err.Error()), nil)
+		}
+		return
+	}
+
+	writeData(wr, http.StatusOK, j.report())
+}
+
+func writeJobNotFound(wr http.ResponseWriter) {
+	writeError(wr, http.StatusNotFound, errCodeJobNotFound, "job not found", nil)
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"kps-migration-api/model"
+
+	"github.com/gorilla/mux"
+)
+
+// scheduler를 거치지 않고 실행 중인 job을 등록한다.
+func newRunningJob(s *jobStore, operation string, method string, request model.SyncRequest) *job {
+	j := s.create(operation, method, request)
+	j.mu.Lock()
+	j.status.State = model.JobStateRunning
+	j.mu.Unlock()
+	return j
+}
+
+// 테스트가 시작한 job을 중지하고 job goroutine이 끝날 때까지 기다린다.
+// rclone mock과 설정을 되돌리기 전에 호출해 다음 테스트와 겹치지 않게 한다.
+func stopJobRoutines(t *testing.T) {
+	t.Helper()
+	wasDraining := draining.Swap(true)
+	for _, j := range jobs.list() {
+		j.cancel()
+	}
+	draining.Store(wasDraining)
+
+	done := make(chan struct{})
+	go func() {
+		jobRoutines.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("job goroutines did not stop")
+	}
+}
+
+func TestParseRcloneJobId(t *testing.T) {
+	id, err := parseRcloneJobId(`{"jobid":42}`)
+	if err != nil || id != 42 {
+		t.Fatalf("expected job id 42, got %d (err=%v)", id, err)
+	}
+	if _, err := parseRcloneJobId(`{"result":"ok"}`); err == nil {
+		t.Fatalf("expected error when jobid is missing")
+	}
+}
+
+func TestJobRefresh_RunningThenSuccess(t *testing.T) {
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(newJobStore(), "sync", "sync/sync", model.SyncRequest{})
+
+		setOut(`{"id":1,"finished":false}`)
+		if j.refresh(0) {
+			t.Fatalf("expected running job not to be finished")
+		}
+		if *lastMethod != "job/status" {
+			t.Fatalf("expected method job/status, got %q", *lastMethod)
+		}
+
+		setOut(`{"id":1,"finished":true,"success":true,"endTime":"2025-01-01T00:00:00Z","output":{}}`)
+		if !j.refresh(0) {
+			t.Fatalf("expected job to be finished")
+		}
+		status := j.snapshot()
+		if status.State != model.JobStateSuccess || status.EndTime == nil {
+			t.Fatalf("expected success with end time, got %+v", status)
+		}
+	})
+}
+
+func TestJobRefresh_Error(t *testing.T) {
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(newJobStore(), "copy", "sync/copy", model.SyncRequest{})
+
+		setOut(`{"id":1,"finished":true,"success":false,"error":"directory not found"}`)
+		if !j.refresh(0) {
+			t.Fatalf("expected job to be finished")
+		}
+		status := j.snapshot()
+		if status.State != model.JobStateError || status.Error != "directory not found" {
+			t.Fatalf("expected error state, got %+v", status)
+		}
+	})
+}
+
+func TestJobStatus_NotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/unknown", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "unknown"})
+	w := httptest.NewRecorder()
+
+	jobStatus(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestJobStatus_Found(t *testing.T) {
+	j := newRunningJob(jobs, "sync", "sync/sync", model.SyncRequest{})
+	defer jobs.remove(j.id)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/"+j.id, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": j.id})
+	w := httptest.NewRecorder()
+
+	jobStatus(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestJobRefresh_IgnoresStaleRcloneJob(t *testing.T) {
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(newJobStore(), "sync", "sync/sync", model.SyncRequest{})
+		j.rcloneJobId = 2
+
+		setOut(`{"id":1,"finished":true,"success":false,"error":"context canceled"}`)
+		j.refresh(1)
+
+		if status := j.snapshot(); status.State != model.JobStateRunning {
+			t.Fatalf("expected stale result to be ignored, got %+v", status)
+		}
+	})
+}
+
+func TestJob_PauseResumeCancel(t *testing.T) {
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(newJobStore(), "copy", "sync/copy", model.SyncRequest{SrcFs: "src:", DstFs: "dst:"})
+
+		setOut(`{}`)
+		if err := j.pause(); err != nil {
+			t.Fatalf("pause: %v", err)
+		}
+		if *lastMethod != "job/stop" {
+			t.Fatalf("expected method job/stop, got %q", *lastMethod)
+		}
+		if err := j.pause(); err != errJobNotActive {
+			t.Fatalf("expected errJobNotActive when pausing twice, got %v", err)
+		}
+
+		setOut(`{"jobid":7}`)
+		if err := j.resume(); err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if *lastMethod != "sync/copy" || j.rcloneJobId != 7 {
+			t.Fatalf("expected copy to restart as rclone job 7, got %q %d", *lastMethod, j.rcloneJobId)
+		}
+
+		setOut(`{}`)
+		if err := j.cancel(); err != nil {
+			t.Fatalf("cancel: %v", err)
+		}
+
+		status := j.snapshot()
+		if status.State != model.JobStateCancelled || status.EndTime == nil {
+			t.Fatalf("expected cancelled job with end time, got %+v", status)
+		}
+		actions := []string{}
+		for _, a := range status.Actions {
+			actions = append(actions, a.Action)
+		}
+		if len(actions) != 3 || actions[0] != "pause" || actions[1] != "resume" || actions[2] != "cancel" {
+			t.Fatalf("expected pause, resume, cancel actions, got %v", actions)
+		}
+		if err := j.cancel(); err != errJobNotActive {
+			t.Fatalf("expected errJobNotActive when cancelling twice, got %v", err)
+		}
+	})
+}
+
+func TestJob_PauseResumeFinishKeepsTotals(t *testing.T) {
+	sink := withAuditSink(t)
+	withRcloneResponses(t, nil)
+	var mu gosync.Mutex
+	var resets []string
+	runs, bytes := 0, int64(0)
+	// 첫 실행은 100 byte, 재개한 실행은 50 byte를 전송하고 끝난다.
+	rcloneRPC = func(method, in string) (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "sync/copy":
+			runs++
+			bytes = int64(150 - 50*runs)
+			return fmt.Sprintf(`{"jobid":%d}`, runs), http.StatusOK
+		case "core/stats":
+			return fmt.Sprintf(`{"bytes":%d,"transfers":%d}`, bytes, bytes/50), http.StatusOK
+		case "core/stats-reset":
+			resets = append(resets, in)
+			bytes = 0
+		case "job/status":
+			if runs == 2 {
+				return `{"id":2,"finished":true,"success":true}`, http.StatusOK
+			}
+			return `{"id":1,"finished":false}`, http.StatusOK
+		}
+		return `{}`, http.StatusOK
+	}
+
+	j := newRunningJob(newJobStore(), "copy", "sync/copy", model.SyncRequest{})
+	j.setAuditRecord(model.AuditRecord{Operation: "copy"})
+	if _, err := j.run(); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if err := j.pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if err := j.resume(); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	waitForState(t, j, model.JobStateSuccess)
+
+	mu.Lock()
+	if len(resets) != 1 || resets[0] != `{"group":"job/`+j.id+`"}` {
+		t.Fatalf("expected stats of the job group to be reset once on resume, got %v", resets)
+	}
+	mu.Unlock()
+	if progress, ok := j.transferStats(); !ok || progress.Bytes != 150 || progress.Transfers != 3 {
+		t.Fatalf("expected final progress to include bytes before the pause, got %+v", progress)
+	}
+	records := sink.snapshot()
+	if len(records) != 1 || records[0].Bytes != 150 || records[0].Objects != 3 {
+		t.Fatalf("expected audit record with all transferred bytes, got %+v", records)
+	}
+}
+
+func TestCancelJob_Conflict(t *testing.T) {
+	j := newRunningJob(jobs, "sync", "sync/sync", model.SyncRequest{})
+	defer jobs.remove(j.id)
+	j.finish(0, model.RcloneJobStatus{Success: true})
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/migration/jobs/"+j.id, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": j.id})
+	w := httptest.NewRecorder()
+
+	cancelJob(w, req)
+
+	if w.Result().StatusCode != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Result().StatusCode)
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	gosync "sync"
+	"time"
+)
+
+// kid가 없는 요청과 PrivateKey 설정의 키에 사용하는 kid
+const defaultKid = "default"
+
+// PrivateKeyDir의 키. 파일 이름 <kid>.pem이 kid가 되고,
+// <kid>.retire 파일에 RFC3339 시각을 쓰면 그 시각부터 키를 사용하지 않는다.
+type privateKey struct {
+	kid      string
+	key      *rsa.PrivateKey
+	retireAt time.Time
+}
+
+func (k *privateKey) active(now time.Time) bool {
+	return k.retireAt.IsZero() || now.Before(k.retireAt)
+}
+
+type keyring struct {
+	keys map[string]*privateKey
+}
+
+// 키 디렉토리를 다시 확인하는 간격. mount된 Secret이 바뀌면 재시작 없이 새 키를 읽는다.
+var keyringCheckInterval = 30 * time.Second
+
+var currentKeyring = (&keyringLoader{}).get
+
+// 키 디렉토리의 파일 이름, 크기, 수정 시각이 바뀌면 keyring을 다시 읽는다.
+type keyringLoader struct {
+	mu          gosync.Mutex
+	dir         string
+	fingerprint string
+	checkedAt   time.Time
+	ring        *keyring
+	err         error
+}
+
+func (l *keyringLoader) get() (*keyring, error) {
+	dir := config.Env.PrivateKeyDir
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	loaded := !l.checkedAt.IsZero() && dir == l.dir
+	if loaded && now.Sub(l.checkedAt) < keyringCheckInterval {
+		return l.ring, l.err
+	}
+	l.checkedAt = now
+
+	fingerprint, err := keyDirFingerprint(dir)
+	if loaded && err == nil && fingerprint == l.fingerprint {
+		return l.ring, l.err
+	}
+	ring, err := loadKeyring(dir)
+	if err != nil && loaded && l.ring != nil {
+		// Secret이 갱신되는 도중이거나 잘못된 키가 들어오면 이전 키를 계속 사용한다.
+		logger.Error("keyring reload failed, keeping previous keys", "dir", dir, "error", err)
+		return l.ring, nil
+	}
+	if err == nil && loaded {
+		logger.Info("keyring reloaded", "dir", dir, "keys", len(ring.keys))
+	}
+	l.dir, l.fingerprint, l.ring, l.err = dir, fingerprint, ring, err
+	return l.ring, l.err
+}
+
+func keyDirFingerprint(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	var files []string
+	for _, pattern := range []string{"*.pem", "*.retire"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return "", err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var b strings.Builder
+	for _, file := range files {
+		// Secret volume의 파일은 symlink이므로 Stat으로 실제 파일을 확인한다.
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", filepath.Base(file), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func loadKeyring(dir string) (*keyring, error) {
+	ring := &keyring{keys: make(map[string]*privateKey)}
+	if dir == "" {
+		return ring, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if kid == defaultKid {
+			return nil, fmt.Errorf("kid %q is reserved for PrivateKey", defaultKid)
+		}
+
+		pemData, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePrivateKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+		k := &privateKey{kid: kid, key: key}
+
+		retire, err := os.ReadFile(filepath.Join(dir, kid+".retire"))
+		if err == nil {
+			if k.retireAt, err = time.Parse(time.RFC3339, strings.TrimSpace(string(retire))); err != nil {
+				return nil, fmt.Errorf("key %s: invalid retirement time: %w", kid, err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		ring.keys[kid] = k
+	}
+	return ring, nil
+}
+
+// 사용할 수 있는 키를 찾는다. 폐기 시각이 지난 키는 사용할 수 없다.
+func (r *keyring) lookup(kid string, now time.Time) (*privateKey, error) {
+	k, ok := r.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if !k.active(now) {
+		return nil, fmt.Errorf("key %q was retired at %s", kid, k.retireAt.Format(time.RFC3339))
+	}
+	return k, nil
+}
+
+// 사용할 수 있는 키 목록. 폐기 예정이 없는 키를 먼저, 그 다음 늦게 폐기되는 순서로 정렬한다.
+func (r *keyring) activeKeys(now time.Time) []*privateKey {
+	var keys []*privateKey
+	for _, k := range r.keys {
+		if k.active(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.retireAt.Equal(b.retireAt) {
+			return a.kid < b.kid
+		}
+		if a.retireAt.IsZero() || b.retireAt.IsZero() {
+			return a.retireAt.IsZero()
+		}
+		return a.retireAt.After(b.retireAt)
+	})
+	return keys
+}
+
+func parsePrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	priInterface, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := priInterface.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+func rsaDecrypt(key *rsa.PrivateKey, cipherText []byte) ([]byte, error) {
+	return key.Decrypt(nil, cipherText, &rsa.OAEPOptions{Hash: crypto.SHA256})
+}
+
+// kid가 없거나 default이면 PrivateKey 설정의 키를, 그 외에는 PrivateKeyDir의 키를 사용한다.
+func rsaDecodeKid(kid string, cipherText []byte) ([]byte, error) {
+	if kid == "" || kid == defaultKid {
+		return rsaDecodeFunc(cipherText)
+	}
+
+	ring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	k, err := ring.lookup(kid, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return rsaDecrypt(k.key, cipherText)
+}
+
+func publicJwk(kid string, key *rsa.PrivateKey, retireAt time.Time) model.Jwk {
+	jwk := model.Jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "enc",
+		Alg: "RSA-OAEP-256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+	if !retireAt.IsZero() {
+		jwk.Exp = retireAt.Unix()
+	}
+	return jwk
+}
+
+// @Summary Publish encryption public keys
+// @Description Publish the RSA public keys used to wrap the AES key of model.HybridPayload as a JWKS.
+// @Description Send the "kid" of the chosen key in the payload. The first key is the preferred one.
+// @Description A key with "exp" (unix time) is being retired and is rejected after that time.
+// @Tags Encryption
+// @Produce json
+// @Success 200 {object} model.Jwks
+// @Failure 500 {object} model.Response
+// @Router /v1/migration/encryption/jwks.json [get]
+func encryptionKeys(wr http.ResponseWriter, r *http.Request) {
+	ring, err := currentKeyring()
+	if err != nil {
+		writeError(wr, http.StatusInternalServerError, errCodeInternal, "encryption keys are not available", nil)
+		return
+	}
+
+	jwks := model.Jwks{Keys: []model.Jwk{}}
+	for _, k := range ring.activeKeys(time.Now()) {
+		jwks.Keys = append(jwks.Keys, publicJwk(k.kid, k.key, k.retireAt))
+	}
+	if config.Env.PrivateKey != "" {
+		if key, err := parsePrivateKey([]byte(config.Env.PrivateKey)); err == nil {
+			jwks.Keys = append(jwks.Keys, publicJwk(defaultKid, key, time.Time{}))
+		}
+	}
+
+	// JWKS 형식을 그대로 사용하는 클라이언트를 위해 응답 envelope을 사용하지 않는다.
+	wr.Header().Set("Content-type", "application/json")
+	wr.Header().Set("Cache-Control", "max-age=300")
+	wr.WriteHeader(http.StatusOK)
+	json.NewEncoder(wr).Encode(jwks)
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+func writeTestKey(t *testing.T, dir string, kid string, retireAt string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey error: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pemData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if retireAt != "" {
+		if err := os.WriteFile(filepath.Join(dir, kid+".retire"), []byte(retireAt+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return key
+}
+
+func withTestKeyring(t *testing.T, dir string) {
+	t.Helper()
+	ring, err := loadKeyring(dir)
+	if err != nil {
+		t.Fatalf("loadKeyring error: %v", err)
+	}
+	old := currentKeyring
+	t.Cleanup(func() { currentKeyring = old })
+	currentKeyring = func() (*keyring, error) { return ring, nil }
+}
+
+func TestKeyring_LookupAndRetirement(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeTestKey(t, dir, "2026-10", "")
+	writeTestKey(t, dir, "2026-04", now.Add(24*time.Hour).Format(time.RFC3339))
+	writeTestKey(t, dir, "2025-10", now.Add(-time.Hour).Format(time.RFC3339))
+
+	ring, err := loadKeyring(dir)
+	if err != nil {
+		t.Fatalf("loadKeyring error: %v", err)
+	}
+
+	if _, err := ring.lookup("2026-04", now); err != nil {
+		t.Fatalf("expected retiring key to be valid until its retirement, got %v", err)
+	}
+	if _, err := ring.lookup("2025-10", now); err == nil {
+		t.Fatalf("expected retired key to be rejected")
+	}
+	if _, err := ring.lookup("unknown", now); err == nil {
+		t.Fatalf("expected unknown kid to be rejected")
+	}
+
+	keys := ring.activeKeys(now)
+	if len(keys) != 2 || keys[0].kid != "2026-10" || keys[1].kid != "2026-04" {
+		t.Fatalf("expected current key first and retired key excluded, got %+v", keys)
+	}
+}
+
+func TestLoadKeyring_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKey(t, dir, defaultKid, "")
+	if _, err := loadKeyring(dir); err == nil {
+		t.Fatalf("expected reserved kid to be rejected")
+	}
+
+	dir = t.TempDir()
+	writeTestKey(t, dir, "k1", "next month")
+	if _, err := loadKeyring(dir); err == nil {
+		t.Fatalf("expected invalid retirement time to be rejected")
+	}
+
+	dir = t.TempDir()
+	os.WriteFile(filepath.Join(dir, "k1.pem"), []byte("not a key"), 0600)
+	if _, err := loadKeyring(dir); err == nil {
+		t.Fatalf("expected invalid PEM to be rejected")
+	}
+}
+
+func TestRsaDecodeKid(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir, "k1", "")
+	withTestKeyring(t, dir)
+
+	cipherText, err := rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, &key.PublicKey, []byte("aes-key"), nil)
+	if err != nil {
+		t.Fatalf("EncryptOAEP error: %v", err)
+	}
+	plain, err := rsaDecodeKid("k1", cipherText)
+	if err != nil || string(plain) != "aes-key" {
+		t.Fatalf("expected aes-key, got %q (%v)", plain, err)
+	}
+	if _, err := rsaDecodeKid("k2", cipherText); err == nil {
+		t.Fatalf("expected error for unknown kid")
+	}
+
+	// kid가 없으면 PrivateKey 설정의 키를 사용한다.
+	oldRsa := rsaDecodeFunc
+	defer func() { rsaDecodeFunc = oldRsa }()
+	rsaDecodeFunc = func(b []byte) ([]byte, error) { return []byte("legacy"), nil }
+	if plain, _ := rsaDecodeKid("", cipherText); string(plain) != "legacy" {
+		t.Fatalf("expected default key for empty kid, got %q", plain)
+	}
+}
+
+func TestEncryptionKeys_PublishesJwks(t *testing.T) {
+	dir := t.TempDir()
+	key := writeTestKey(t, dir, "k1", "")
+	writeTestKey(t, dir, "old", time.Now().Add(-time.Minute).Format(time.RFC3339))
+	withTestKeyring(t, dir)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/encryption/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler().ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	var jwks model.Jwks
+	if err := json.NewDecoder(w.Body).Decode(&jwks); err != nil {
+		t.Fatalf("decode jwks: %v", err)
+	}
+	if len(jwks.Keys) == 0 || jwks.Keys[0].Kid != "k1" || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].E != "AQAB" {
+		t.Fatalf("unexpected jwks %+v", jwks)
+	}
+	if jwks.Keys[0].N != base64.RawURLEncoding.EncodeToString(key.N.Bytes()) {
+		t.Fatalf("expected the public modulus of k1")
+	}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kid == "old" {
+			t.Fatalf("expected retired key to be unpublished")
+		}
+	}
+}
+
+func publishedKids(t *testing.T) []string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	encryptionKeys(w, httptest.NewRequest(http.MethodGet, "/v1/migration/encryption/jwks.json", nil))
+	var jwks model.Jwks
+	if err := json.NewDecoder(w.Body).Decode(&jwks); err != nil {
+		t.Fatalf("decode jwks: %v", err)
+	}
+	var kids []string
+	for _, jwk := range jwks.Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	return kids
+}
+
+func TestKeyring_ReloadsRotatedKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeTestKey(t, dir, "k1", "")
+
+	oldEnv, oldCurrent, oldInterval := config.Env, currentKeyring, keyringCheckInterval
+	t.Cleanup(func() { config.Env, currentKeyring, keyringCheckInterval = oldEnv, oldCurrent, oldInterval })
+	env := *oldEnv
+	env.PrivateKeyDir = dir
+	env.PrivateKey = ""
+	config.Env = &env
+	currentKeyring = (&keyringLoader{}).get
+	keyringCheckInterval = 0
+
+	if kids := publishedKids(t); len(kids) != 1 || kids[0] != "k1" {
+		t.Fatalf("expected k1, got %v", kids)
+	}
+
+	// 프로세스를 재시작하지 않고 새 키를 추가하고 이전 키를 폐기한다.
+	key := writeTestKey(t, dir, "k2", "")
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	if err := os.WriteFile(filepath.Join(dir, "k1.retire"), []byte(past), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if kids := publishedKids(t); len(kids) != 1 || kids[0] != "k2" {
+		t.Fatalf("expected rotated key k2, got %v", kids)
+	}
+	cipherText, _ := rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, &key.PublicKey, []byte("aes-key"), nil)
+	if plain, err := rsaDecodeKid("k2", cipherText); err != nil || string(plain) != "aes-key" {
+		t.Fatalf("expected the new key to decrypt, got %q (%v)", plain, err)
+	}
+
+	// 잘못된 키가 들어오면 이전 keyring을 계속 사용한다.
+	os.WriteFile(filepath.Join(dir, "k3.pem"), []byte("not a key"), 0600)
+	if kids := publishedKids(t); len(kids) != 1 || kids[0] != "k2" {
+		t.Fatalf("expected previous keys after failed reload, got %v", kids)
+	}
+
+	// 확인 간격 안에서는 디렉토리를 다시 읽지 않는다.
+	keyringCheckInterval = time.Hour
+	os.Remove(filepath.Join(dir, "k3.pem"))
+	writeTestKey(t, dir, "k4", "")
+	if kids := publishedKids(t); len(kids) != 1 || kids[0] != "k2" {
+		t.Fatalf("expected cached keyring within the check interval, got %v", kids)
+	}
+}
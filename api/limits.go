@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"kps-migration-api/config"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 요청 본문을 MaxRequestBodySize로 제한하고, RouteTimeouts에 있는 route는 처리 시간을 바꾼다.
+func requestLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		if limit := config.Env.MaxRequestBodySize; limit > 0 {
+			if r.ContentLength > limit {
+				writeError(wr, http.StatusRequestEntityTooLarge, errCodeRequestTooLarge, "request body is too large", map[string]interface{}{"limit": limit})
+				return
+			}
+			r.Body = http.MaxBytesReader(wr, r.Body, limit)
+		}
+
+		if route := mux.CurrentRoute(r); route != nil {
+			template, _ := route.GetPathTemplate()
+			if timeout, ok := config.Env.RouteTimeout(template); ok {
+				// 서버의 WriteTimeout 대신 route의 timeout까지 응답할 수 있다.
+				_ = http.NewResponseController(wr).SetWriteDeadline(time.Now().Add(timeout))
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+		}
+		next.ServeHTTP(wr, r)
+	})
+}
+
+// 요청 본문 decode 실패의 응답 status와 error.code. nonce 저장소 오류 외에는 요청의 문제이다.
+func decodeFailure(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, errCodeRequestTooLarge
+	case errors.Is(err, errRequestExpired):
+		return http.StatusUnauthorized, errCodeRequestExpired
+	case errors.Is(err, errReplayedRequest):
+		return http.StatusConflict, errCodeReplayedRequest
+	case errors.Is(err, errNonceStore):
+		return http.StatusInternalServerError, errCodeInternal
+	}
+	return http.StatusBadRequest, errCodeInvalidRequest
+}
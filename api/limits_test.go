@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kps-migration-api/config"
+)
+
+func TestRequestLimit_BodyTooLarge(t *testing.T) {
+	oldEnv := config.Env
+	t.Cleanup(func() { config.Env = oldEnv })
+	env := *oldEnv
+	env.IsEncryption = "false"
+	env.AuthDisabled = true
+	env.MaxRequestBodySize = 16
+	config.Env = &env
+	withAuditSink(t)
+
+	body := `{"storageType":"s3","bucket":"` + strings.Repeat("b", 64) + `"}`
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", strings.NewReader(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+
+	// Content-Length가 없는 본문은 읽는 중에 제한한다.
+	req := httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", bytes.NewBufferString(body))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for chunked body, got %d", w.Code)
+	}
+	resp := decodeResponse(t, w.Body, nil)
+	if resp.Error == nil || resp.Error.Code != errCodeRequestTooLarge {
+		t.Fatalf("expected %s, got %+v", errCodeRequestTooLarge, resp.Error)
+	}
+
+	// 암호화 요청도 복호화 전에 본문 크기를 확인한다.
+	env.IsEncryption = "true"
+	req = httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewBufferString(`{"encryptedData":"`+strings.Repeat("a", 64)+`"}`))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for chunked encrypted body, got %d", w.Code)
+	}
+}
+
+func TestDecodeFailure(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{&http.MaxBytesError{Limit: 16}, http.StatusRequestEntityTooLarge, errCodeRequestTooLarge},
+		{errors.New("invalid character 'n'"), http.StatusBadRequest, errCodeInvalidRequest},
+		{errPayloadDecryption, http.StatusBadRequest, errCodeInvalidRequest},
+		{errRequestExpired, http.StatusUnauthorized, errCodeRequestExpired},
+		{errReplayedRequest, http.StatusConflict, errCodeReplayedRequest},
+		{fmt.Errorf("%w: %v", errNonceStore, errors.New("connection refused")), http.StatusInternalServerError, errCodeInternal},
+	}
+	for _, tt := range tests {
+		if status, code := decodeFailure(tt.err); status != tt.status || code != tt.code {
+			t.Errorf("%v: expected %d %s, got %d %s", tt.err, tt.status, tt.code, status, code)
+		}
+	}
+}
+
+func TestNewHandler_FeatureToggles(t *testing.T) {
+	oldEnv := config.Env
+	t.Cleanup(func() { config.Env = oldEnv })
+	env := *oldEnv
+	env.SwaggerEnabled = false
+	env.MetricsEnabled = false
+	config.Env = &env
+
+	handler := NewHandler()
+	for _, path := range []string{"/metrics", "/swagger/index.html"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for disabled %s, got %d", path, w.Code)
+		}
+	}
+}
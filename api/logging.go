@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kps-migration-api/config"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+
+	"github.com/rclone/rclone/fs"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIdHeader = "X-Request-Id"
+
+// 요청이 보낸 X-Request-Id는 이 형식일 때만 사용하고, 아니면 새로 만든다.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIdContextKey struct{}
+
+// LogLevel 설정 (debug, info, warn, error). 기본 info
+var logLevel = new(slog.LevelVar)
+
+// 모든 로그는 이 logger로 JSON lines를 남기며 인증 정보는 logHandler에서 가린다.
+var logger = slog.New(newLogHandler(os.Stdout))
+
+// LogLevel을 적용하고 표준 log 패키지와 rclone 로그를 logger로 보낸다.
+func SetupLogging() {
+	if config.Env.LogLevel != "" {
+		if err := logLevel.UnmarshalText([]byte(config.Env.LogLevel)); err != nil {
+			logger.Error("log config error", "error", err)
+		}
+	}
+	slog.SetDefault(logger)
+
+	fs.LogOutput = logRclone
+	// rclone은 기본적으로 NOTICE 이상만 남긴다.
+	if logLevel.Level() <= slog.LevelDebug {
+		fs.GetConfig(context.Background()).LogLevel = fs.LogLevelDebug
+	}
+}
+
+// rclone 로그 레벨을 slog 레벨로 바꿔 기록한다.
+func logRclone(level fs.LogLevel, text string) {
+	var slogLevel slog.Level
+	switch {
+	case level >= fs.LogLevelDebug:
+		slogLevel = slog.LevelDebug
+	case level >= fs.LogLevelNotice:
+		slogLevel = slog.LevelInfo
+	case level == fs.LogLevelWarning:
+		slogLevel = slog.LevelWarn
+	default:
+		slogLevel = slog.LevelError
+	}
+	logger.Log(context.Background(), slogLevel, text, "component", "rclone")
+}
+
+// 요청 ID를 context와 응답 헤더에 넣는다. 같은 요청에서 남기는 로그에는 request_id가 붙는다.
+func requestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIdHeader)
+		if !requestIdPattern.MatchString(id) {
+			id = newRequestId()
+		}
+		wr.Header().Set(requestIdHeader, id)
+		next.ServeHTTP(wr, r.WithContext(context.WithValue(r.Context(), requestIdContextKey{}, id)))
+	})
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// context의 요청 ID, 사용자, trace ID를 붙이고 메시지와 속성의 인증 정보를 가리는 slog.Handler
+type logHandler struct {
+	slog.Handler
+}
+
+func newLogHandler(w io.Writer) *logHandler {
+	return &logHandler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: logLevel})}
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, redactLog(r.Message), r.PC)
+	if id, ok := ctx.Value(requestIdContextKey{}).(string); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if c, ok := callerFromContext(ctx); ok {
+		record.AddAttrs(slog.String("user", c.Subject))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = redactAttr(a)
+	}
+	return &logHandler{Handler: h.Handler.WithAttrs(redactedAttrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// 인증 정보 속성은 값 전체를, 나머지는 값 안의 인증 정보를 가린다.
+// 구조체 등은 JSON으로 바꾼 뒤 가린다.
+func redactAttr(a slog.Attr) slog.Attr {
+	if secretLogKeys[normalizeLogKey(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactLog(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redactedGroup := make([]any, len(group))
+		for i, member := range group {
+			redactedGroup[i] = redactAttr(member)
+		}
+		return slog.Group(a.Key, redactedGroup...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return slog.String(a.Key, redactLog(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, redactLog(v.String()))
+		default:
+			var decoded interface{}
+			byt, err := json.Marshal(v)
+			if err == nil {
+				err = json.Unmarshal(byt, &decoded)
+			}
+			if err != nil {
+				return slog.String(a.Key, redactLog(fmt.Sprintf("%+v", v)))
+			}
+			return slog.Any(a.Key, redactJSONValue(decoded))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: value}
+}
+
+func redactJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretLogKeys[normalizeLogKey(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSONValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactJSONValue(value)
+		}
+	case string:
+		return redactLog(v)
+	}
+	return v
+}
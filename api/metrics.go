@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"kps-migration-api/model"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// /metrics로 노출하는 registry. rclone이 default registry에 등록하는 metric과 섞이지 않게 따로 둔다.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "migration_http_requests_total",
+		Help: "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "migration_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	rcloneRPCDuration = promauto.With(metricsRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "migration_rclone_rpc_duration_seconds",
+		Help:    "rclone rc call duration by method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "status"})
+
+	jobsFinishedTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "migration_jobs_finished_total",
+		Help: "Finished migration jobs by operation and final state.",
+	}, []string{"operation", "state"})
+	transferredBytesTotal = promauto.With(metricsRegistry).NewCounter(prometheus.CounterOpts{
+		Name: "migration_transferred_bytes_total",
+		Help: "Bytes transferred by finished migration jobs.",
+	})
+	transferredObjectsTotal = promauto.With(metricsRegistry).NewCounter(prometheus.CounterOpts{
+		Name: "migration_transferred_objects_total",
+		Help: "Objects transferred by finished migration jobs.",
+	})
+	deletedObjectsTotal = promauto.With(metricsRegistry).NewCounter(prometheus.CounterOpts{
+		Name: "migration_deleted_objects_total",
+		Help: "Objects deleted by finished migration jobs.",
+	})
+	transferErrorsTotal = promauto.With(metricsRegistry).NewCounter(prometheus.CounterOpts{
+		Name: "migration_transfer_errors_total",
+		Help: "Transfer errors of finished migration jobs.",
+	})
+
+	// reason: unsupported_alg, decryption, hmac, expired, replay, nonce
+	payloadFailuresTotal = promauto.With(metricsRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "migration_payload_failures_total",
+		Help: "Rejected encrypted request payloads by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		&jobCollector{store: jobs},
+	)
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// http.ResponseController가 Flush, SetWriteDeadline을 원래 ResponseWriter에 전달할 수 있게 한다.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// route template별 요청 수와 처리 시간을 기록한다.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: wr, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		httpRequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+func instrumentRcloneRPC(rpc func(method string, input string) (string, int)) func(method string, input string) (string, int) {
+	return func(method string, input string) (string, int) {
+		start := time.Now()
+		out, status := rpc(method, input)
+		observeRcloneRPC(method, status, start)
+		return out, status
+	}
+}
+
+func observeRcloneRPC(method string, status int, start time.Time) {
+	rcloneRPCDuration.WithLabelValues(method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+}
+
+// 종료된 job의 전송량을 누적한다.
+func observeJobEnd(status model.JobStatus, progress model.JobProgress) {
+	jobsFinishedTotal.WithLabelValues(status.Operation, string(status.State)).Inc()
+	transferredBytesTotal.Add(float64(progress.Bytes))
+	transferredObjectsTotal.Add(float64(progress.Transfers))
+	deletedObjectsTotal.Add(float64(progress.Deletes))
+	transferErrorsTotal.Add(float64(progress.Errors))
+}
+
+// 보관 중인 job의 상태별 개수와 job별 전송량. 실행 중인 job은 scrape 시점의 rclone stats를 조회한다.
+type jobCollector struct {
+	store *jobStore
+}
+
+var (
+	jobsDesc = prometheus.NewDesc("migration_jobs",
+		"Migration jobs kept by the service by state.", []string{"state"}, nil)
+	jobBytesDesc = prometheus.NewDesc("migration_job_transferred_bytes",
+		"Bytes transferred by a migration job.", []string{"job_id", "operation"}, nil)
+	jobObjectsDesc = prometheus.NewDesc("migration_job_transferred_objects",
+		"Objects transferred by a migration job.", []string{"job_id", "operation"}, nil)
+	jobErrorsDesc = prometheus.NewDesc("migration_job_transfer_errors",
+		"Transfer errors of a migration job.", []string{"job_id", "operation"}, nil)
+	jobStartDesc = prometheus.NewDesc("migration_job_start_time_seconds",
+		"Start time of a migration job in unix seconds.", []string{"job_id", "operation", "state"}, nil)
+)
+
+var jobMetricStates = []model.JobState{
+	model.JobStatePending, model.JobStateQueued, model.JobStateRunning, model.JobStatePaused, model.JobStateInterrupted,
+	model.JobStateSuccess, model.JobStateError, model.JobStateCancelled,
+}
+
+func (c *jobCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- jobsDesc
+	ch <- jobBytesDesc
+	ch <- jobObjectsDesc
+	ch <- jobErrorsDesc
+	ch <- jobStartDesc
+}
+
+func (c *jobCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := make(map[model.JobState]int)
+	for _, j := range c.store.list() {
+		status := j.snapshot()
+		counts[status.State]++
+
+		ch <- prometheus.MustNewConstMetric(jobStartDesc, prometheus.GaugeValue,
+			float64(status.StartTime.Unix()), status.JobId, status.Operation, string(status.State))
+		progress, ok := j.transferStats()
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(jobBytesDesc, prometheus.GaugeValue, float64(progress.Bytes), status.JobId, status.Operation)
+		ch <- prometheus.MustNewConstMetric(jobObjectsDesc, prometheus.GaugeValue, float64(progress.Transfers), status.JobId, status.Operation)
+		ch <- prometheus.MustNewConstMetric(jobErrorsDesc, prometheus.GaugeValue, float64(progress.Errors), status.JobId, status.Operation)
+	}
+	for _, state := range jobMetricStates {
+		ch <- prometheus.MustNewConstMetric(jobsDesc, prometheus.GaugeValue, float64(counts[state]), string(state))
+	}
+}
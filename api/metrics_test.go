@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+// metricsRegistry에서 name과 labels가 일치하는 counter 또는 gauge 값을 찾는다.
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		t.Fatalf("Gather error: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					continue metrics
+				}
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestMetrics_HttpRequests(t *testing.T) {
+	withAuthDisabled(t)
+	labels := map[string]string{"route": "/v1/migration/jobs/{id}", "method": "GET", "status": "404"}
+	before := metricValue(t, "migration_http_requests_total", labels)
+
+	handler := NewHandler()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/missing", nil))
+
+	if got := metricValue(t, "migration_http_requests_total", labels); got != before+1 {
+		t.Fatalf("expected %v requests, got %v", before+1, got)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	for _, want := range []string{
+		`migration_http_request_duration_seconds_bucket{method="GET",route="/v1/migration/jobs/{id}"`,
+		`migration_jobs{state="running"}`,
+		`go_goroutines`,
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Fatalf("expected %s in metrics output", want)
+		}
+	}
+}
+
+func TestMetrics_JobTransfers(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	allowLocalStorage(t, "/")
+	withAuditSink(t)
+	withRcloneResponses(t, map[string]string{
+		"sync/sync":  `{"jobid":9}`,
+		"job/status": `{"id":9,"finished":true,"success":true,"endTime":"2026-01-01T00:00:00Z"}`,
+		"core/stats": `{"bytes":4096,"transfers":2,"deletes":5,"errors":1}`,
+	})
+	finished := map[string]string{"operation": "sync", "state": "success"}
+	beforeJobs := metricValue(t, "migration_jobs_finished_total", finished)
+	beforeBytes := metricValue(t, "migration_transferred_bytes_total", nil)
+	beforeErrors := metricValue(t, "migration_transfer_errors_total", nil)
+
+	body, _ := json.Marshal(model.SyncConfig{
+		Src: model.StorageConfig{StorageType: "local", Bucket: "/src"},
+		Dst: model.StorageConfig{StorageType: "local", Bucket: "/dst"},
+	})
+	w := httptest.NewRecorder()
+	sync(w, httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body)))
+	var accepted model.JobAccepted
+	decodeResponse(t, w.Body, &accepted)
+	t.Cleanup(func() { jobs.remove(accepted.JobId) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for metricValue(t, "migration_jobs_finished_total", finished) == beforeJobs && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := metricValue(t, "migration_jobs_finished_total", finished); got != beforeJobs+1 {
+		t.Fatalf("expected %v finished jobs, got %v", beforeJobs+1, got)
+	}
+	if got := metricValue(t, "migration_transferred_bytes_total", nil); got != beforeBytes+4096 {
+		t.Fatalf("expected %v bytes, got %v", beforeBytes+4096, got)
+	}
+	if got := metricValue(t, "migration_transfer_errors_total", nil); got != beforeErrors+1 {
+		t.Fatalf("expected %v errors, got %v", beforeErrors+1, got)
+	}
+	// 종료된 job은 종료 시점의 전송량을 유지한다.
+	if got := metricValue(t, "migration_job_transferred_objects", map[string]string{"job_id": accepted.JobId}); got != 2 {
+		t.Fatalf("expected 2 objects for the job, got %v", got)
+	}
+}
+
+func TestMetrics_PayloadFailures(t *testing.T) {
+	labels := map[string]string{"reason": "unsupported_alg"}
+	before := metricValue(t, "migration_payload_failures_total", labels)
+
+	if _, _, err := decodeHybridPayload[model.SyncConfig](model.HybridPayload{Alg: "ROT13"}); err == nil {
+		t.Fatal("expected error")
+	}
+	labels = map[string]string{"reason": "decryption"}
+	beforeDecryption := metricValue(t, "migration_payload_failures_total", labels)
+	if _, _, err := decodeHybridPayload[model.SyncConfig](model.HybridPayload{EncryptedData: "!"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if got := metricValue(t, "migration_payload_failures_total", map[string]string{"reason": "unsupported_alg"}); got != before+1 {
+		t.Fatalf("expected %v unsupported_alg failures, got %v", before+1, got)
+	}
+	if got := metricValue(t, "migration_payload_failures_total", labels); got != beforeDecryption+1 {
+		t.Fatalf("expected %v decryption failures, got %v", beforeDecryption+1, got)
+	}
+}
+
+func TestMetrics_EventStreamThroughMiddleware(t *testing.T) {
+	withAuthDisabled(t)
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		j := newRunningJob(jobs, "copy", "sync/copy", model.SyncRequest{})
+		defer jobs.remove(j.id)
+		j.finish(0, model.RcloneJobStatus{Success: true, EndTime: time.Now()})
+
+		w := httptest.NewRecorder()
+		NewHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/"+j.id+"/events", nil))
+		if !strings.Contains(w.Body.String(), "event: done") || !w.Flushed {
+			t.Fatalf("expected flushed done event, got %q", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	gosync "sync"
+	"time"
+)
+
+// 암호화 요청 timestamp의 기본 허용 범위
+const (
+	defaultPayloadMaxAge  = 5 * time.Minute
+	defaultPayloadMaxSkew = 30 * time.Second
+)
+
+// nonce 길이 제한. UUID나 16바이트 이상의 난수를 사용한다.
+const (
+	minNonceLength = 16
+	maxNonceLength = 128
+)
+
+var errReplayedRequest = errors.New("duplicate request")
+
+// nonce 저장소 오류. 요청이 아니라 서버의 문제이다.
+var errNonceStore = errors.New("nonce store is unavailable")
+
+// 재전송 방지용 nonce 저장소. replica가 여러 개이면 공유 저장소(redis 등)로 구현해 SetNonceStore로 등록한다.
+type NonceStore interface {
+	// nonce를 expiresAt까지 저장한다. 아직 만료되지 않은 같은 nonce가 있으면 false를 반환한다.
+	Add(nonce string, expiresAt time.Time) (bool, error)
+}
+
+var nonceStore NonceStore = newMemoryNonceStore()
+
+func SetNonceStore(store NonceStore) {
+	nonceStore = store
+}
+
+// 프로세스 메모리에 nonce를 보관한다. 만료된 nonce는 1분마다 정리한다.
+type memoryNonceStore struct {
+	mu        gosync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+func newMemoryNonceStore() *memoryNonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Add(nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) > time.Minute {
+		for n, exp := range s.nonces {
+			if !now.Before(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
+
+// 요청 timestamp가 이보다 오래되면 거부한다. (PayloadMaxAge, 예: "5m")
+func payloadMaxAge() time.Duration {
+	return configDuration(config.Env.PayloadMaxAge, defaultPayloadMaxAge)
+}
+
+// 요청 timestamp가 서버 시각보다 이보다 앞서면 거부한다. (PayloadMaxSkew, 예: "30s")
+func payloadMaxSkew() time.Duration {
+	return configDuration(config.Env.PayloadMaxSkew, defaultPayloadMaxSkew)
+}
+
+func configDuration(value string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// nonce를 한 번만 사용할 수 있게 등록한다. timestamp가 유효한 요청에만 호출한다.
+func checkNonce(nonce string, timestampMillis int64) error {
+	if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return errors.New("nonce must be 16 to 128 characters")
+	}
+	// timestamp 검사를 통과할 수 있는 동안만 보관하면 된다.
+	expiresAt := time.UnixMilli(timestampMillis).Add(payloadMaxAge())
+	added, err := nonceStore.Add(nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNonceStore, err)
+	}
+	if !added {
+		return errReplayedRequest
+	}
+	return nil
+}
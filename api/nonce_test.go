@@ -0,0 +1,89 @@
+package api
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+)
+
+func withMemoryNonceStore(t *testing.T) {
+	t.Helper()
+	old := nonceStore
+	t.Cleanup(func() { nonceStore = old })
+	nonceStore = newMemoryNonceStore()
+}
+
+func TestMemoryNonceStore_RejectsDuplicateUntilExpiry(t *testing.T) {
+	store := newMemoryNonceStore()
+
+	if ok, _ := store.Add("n1", time.Now().Add(time.Minute)); !ok {
+		t.Fatalf("expected first nonce to be accepted")
+	}
+	if ok, _ := store.Add("n1", time.Now().Add(time.Minute)); ok {
+		t.Fatalf("expected duplicate nonce to be rejected")
+	}
+
+	store.Add("n2", time.Now().Add(-time.Second))
+	if ok, _ := store.Add("n2", time.Now().Add(time.Minute)); !ok {
+		t.Fatalf("expected expired nonce to be accepted again")
+	}
+}
+
+func TestCheckNonce(t *testing.T) {
+	withMemoryNonceStore(t)
+	now := time.Now().UnixMilli()
+
+	if err := checkNonce("", now); err == nil {
+		t.Fatalf("expected missing nonce to be rejected")
+	}
+	if err := checkNonce("short", now); err == nil {
+		t.Fatalf("expected short nonce to be rejected")
+	}
+	if err := checkNonce("0123456789abcdef", now); err != nil {
+		t.Fatalf("expected nonce to be accepted, got %v", err)
+	}
+	if err := checkNonce("0123456789abcdef", now); err != errReplayedRequest {
+		t.Fatalf("expected %v, got %v", errReplayedRequest, err)
+	}
+}
+
+func TestVaildTimestampCheck_BoundedSkew(t *testing.T) {
+	if config.Env == nil {
+		t.Skip("config.Env is nil")
+	}
+	oldAge, oldSkew := config.Env.PayloadMaxAge, config.Env.PayloadMaxSkew
+	defer func() { config.Env.PayloadMaxAge, config.Env.PayloadMaxSkew = oldAge, oldSkew }()
+
+	now := int64(10_000_000)
+	at := func(offset time.Duration) string { return strconv.FormatInt(now+offset.Milliseconds(), 10) }
+	current := strconv.FormatInt(now, 10)
+
+	config.Env.PayloadMaxAge, config.Env.PayloadMaxSkew = "", ""
+	tests := []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{0, true},
+		{-5 * time.Minute, true},
+		{-5*time.Minute - time.Millisecond, false},
+		{30 * time.Second, true},
+		{31 * time.Second, false},
+		{24 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		if got := vaildTimestampCheck(at(tt.offset), current); got != tt.want {
+			t.Errorf("offset %s: expected %v, got %v", tt.offset, tt.want, got)
+		}
+	}
+
+	config.Env.PayloadMaxAge, config.Env.PayloadMaxSkew = "1m", "5s"
+	if vaildTimestampCheck(at(-2*time.Minute), current) || vaildTimestampCheck(at(10*time.Second), current) {
+		t.Fatalf("expected configured window to be applied")
+	}
+
+	if vaildTimestampCheck("not-a-number", current) {
+		t.Fatalf("expected invalid timestamp to be rejected")
+	}
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// 서명 키를 찾지 못했을 때 JWKS를 다시 받는 최소 간격
+	jwksRefetchInterval = time.Minute
+	// JWKS를 주기적으로 다시 받는 간격
+	jwksCacheTTL = time.Hour
+	// 서버 간 시각 차이 허용
+	tokenLeeway = 30 * time.Second
+)
+
+// 인증 없이 호출할 수 있는 경로. 같은 경로이거나 그 하위 경로(경로 + "/")만 해당한다.
+var publicPaths = []string{
+	"/actuator/health",
+	"/v1/migration/encryption/jwks.json",
+}
+
+// 토큰에서 확인한 호출자
+type caller struct {
+	Subject  string
+	Username string
+	Email    string
+	Roles    []string
+	Groups   []string
+}
+
+type callerContextKey struct{}
+
+func isPublicPath(path string) bool {
+	paths := publicPaths
+	// swagger와 metrics는 설정한 경우에만 인증 없이 호출할 수 있다.
+	if config.Env.AuthPublicSwagger {
+		paths = append(paths[:len(paths):len(paths)], "/swagger")
+	}
+	if config.Env.AuthPublicMetrics {
+		paths = append(paths[:len(paths):len(paths)], "/metrics")
+	}
+	for _, public := range paths {
+		if path == public || strings.HasPrefix(path, public+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func callerFromContext(ctx context.Context) (*caller, bool) {
+	c, ok := ctx.Value(callerContextKey{}).(*caller)
+	return c, ok
+}
+
+// keycloak access token claims
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	Groups            []string `json:"groups"`
+	RealmAccess       struct {
+		Roles []string `json:"roles"`
+	} `json:"realm_access"`
+	ResourceAccess map[string]struct {
+		Roles []string `json:"roles"`
+	} `json:"resource_access"`
+}
+
+type tokenVerifier struct {
+	parser   *jwt.Parser
+	audience string
+	keys     func(kid string) (crypto.PublicKey, error)
+	// 설정 오류. 설정이 잘못되면 모든 요청을 거부한다.
+	err error
+}
+
+// AuthJwksUrl 또는 AuthPublicKeyFile로 토큰을 검증한다. AuthDisabled이면 nil을 반환한다.
+// 둘 다 없으면 ingress를 거치지 않은 요청도 막도록 설정 오류로 본다.
+func newTokenVerifier() (*tokenVerifier, error) {
+	env := config.Env
+	if env.AuthDisabled {
+		return nil, nil
+	}
+	if env.AuthJwksUrl == "" && env.AuthPublicKeyFile == "" {
+		return nil, errors.New("AuthJwksUrl or AuthPublicKeyFile is required unless AuthDisabled is true")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	}
+	if env.AuthIssuer != "" {
+		options = append(options, jwt.WithIssuer(env.AuthIssuer))
+	}
+	if env.AuthAudience != "" {
+		options = append(options, jwt.WithAudience(env.AuthAudience))
+	}
+	v := &tokenVerifier{parser: jwt.NewParser(options...), audience: env.AuthAudience}
+
+	if env.AuthPublicKeyFile != "" {
+		pemData, err := os.ReadFile(env.AuthPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePublicKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env.AuthPublicKeyFile, err)
+		}
+		v.keys = func(string) (crypto.PublicKey, error) { return key, nil }
+	} else {
+		v.keys = newJwksKeySource(env.AuthJwksUrl).key
+	}
+	return v, nil
+}
+
+func (v *tokenVerifier) verify(tokenString string) (*caller, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	var claims tokenClaims
+	_, err := v.parser.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	c := &caller{
+		Subject:  claims.Subject,
+		Username: claims.PreferredUsername,
+		Email:    claims.Email,
+		Roles:    claims.RealmAccess.Roles,
+		Groups:   claims.Groups,
+	}
+	if v.audience != "" {
+		c.Roles = append(c.Roles, claims.ResourceAccess[v.audience].Roles...)
+	}
+	return c, nil
+}
+
+// Authorization: Bearer 토큰을 검증하고 호출자를 context에 넣는다.
+func authMiddleware(v *tokenVerifier) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+			if isPublicPath(r.URL.Path) {
+				next.ServeHTTP(wr, r)
+				return
+			}
+
+			scheme, tokenString, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || tokenString == "" {
+				wr.Header().Set("WWW-Authenticate", `Bearer`)
+				writeError(wr, http.StatusUnauthorized, errCodeUnauthorized, "missing bearer token", nil)
+				return
+			}
+
+			c, err := v.verify(strings.TrimSpace(tokenString))
+			if err != nil {
+				wr.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(wr, http.StatusUnauthorized, errCodeUnauthorized, "invalid bearer token", nil)
+				return
+			}
+			next.ServeHTTP(wr, r.WithContext(context.WithValue(r.Context(), callerContextKey{}, c)))
+		})
+	}
+}
+
+// PEM 공개키(PKIX) 또는 인증서
+func parsePublicKey(pemData []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// OIDC provider의 JWKS(예: keycloak .../protocol/openid-connect/certs)에서 서명 키를 찾는다.
+type jwksKeySource struct {
+	url       string
+	client    *http.Client
+	mu        gosync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// JWKS를 받는 동안 열려 있고 끝나면 닫힌다.
+	fetching chan struct{}
+}
+
+func newJwksKeySource(url string) *jwksKeySource {
+	return &jwksKeySource{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *jwksKeySource) key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > jwksCacheTTL
+	var err error
+	// 키 교체로 모르는 kid가 오면 다시 받는다. 잘못된 kid로 JWKS 요청이 몰리지 않게 간격을 둔다.
+	if s.fetching == nil && (stale || (!ok && time.Since(s.fetchedAt) > jwksRefetchInterval)) {
+		err = s.refreshLocked()
+		key, ok = s.keys[kid]
+	} else if s.fetching != nil && !ok {
+		// 다른 요청이 받는 중이면 끝날 때까지 기다린다. 알고 있는 키는 기다리지 않고 사용한다.
+		done := s.fetching
+		s.mu.Unlock()
+		<-done
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// mu를 잡은 상태로 호출한다. JWKS를 받는 동안에는 mu를 풀어 다른 요청이 기존 키를 사용할 수 있게 한다.
+func (s *jwksKeySource) refreshLocked() error {
+	done := make(chan struct{})
+	s.fetching = done
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.fetching = nil
+	close(done)
+	return err
+}
+
+func (s *jwksKeySource) fetch() (map[string]crypto.PublicKey, error) {
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", res.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://keycloak.example.com/realms/cp-realm"
+	testAudience = "cp-migration"
+)
+
+func withAuthConfig(t *testing.T, jwksUrl string, publicKeyFile string) {
+	t.Helper()
+	old := *config.Env
+	t.Cleanup(func() { *config.Env = old })
+	config.Env.AuthJwksUrl = jwksUrl
+	config.Env.AuthPublicKeyFile = publicKeyFile
+	config.Env.AuthIssuer = testIssuer
+	config.Env.AuthAudience = testAudience
+}
+
+// token 검증 없이 handler를 호출한다.
+func withAuthDisabled(t *testing.T) {
+	t.Helper()
+	old := config.Env.AuthDisabled
+	t.Cleanup(func() { config.Env.AuthDisabled = old })
+	config.Env.AuthDisabled = true
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString error: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                "user-1",
+		"iss":                testIssuer,
+		"aud":                []string{testAudience, "account"},
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []string{"cp-user"}},
+		"resource_access":    map[string]interface{}{testAudience: map[string]interface{}{"roles": []string{"migrator"}}},
+	}
+}
+
+func jwksServer(t *testing.T, kid string, key *rsa.PublicKey) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func serveWithToken(path string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddleware_Jwks(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	srv := jwksServer(t, "kid-1", &key.PublicKey)
+	withAuthConfig(t, srv.URL, "")
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noExp := validClaims()
+	delete(noExp, "exp")
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "other-client"
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid", signTestToken(t, key, "kid-1", validClaims()), http.StatusNotFound},
+		{"missing", "", http.StatusUnauthorized},
+		{"malformed", "not-a-jwt", http.StatusUnauthorized},
+		{"expired", signTestToken(t, key, "kid-1", expired), http.StatusUnauthorized},
+		{"no exp", signTestToken(t, key, "kid-1", noExp), http.StatusUnauthorized},
+		{"wrong issuer", signTestToken(t, key, "kid-1", wrongIssuer), http.StatusUnauthorized},
+		{"wrong audience", signTestToken(t, key, "kid-1", wrongAudience), http.StatusUnauthorized},
+		{"bad signature", signTestToken(t, other, "kid-1", validClaims()), http.StatusUnauthorized},
+		{"unknown kid", signTestToken(t, other, "kid-2", validClaims()), http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 인증을 통과하면 없는 job 조회로 404가 된다.
+			w := serveWithToken("/v1/migration/jobs/missing", tc.token)
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status == http.StatusUnauthorized {
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Fatal("expected WWW-Authenticate header")
+				}
+				res := decodeResponse(t, w.Body, nil)
+				if res.Error == nil || res.Error.Code != errCodeUnauthorized {
+					t.Fatalf("expected %s, got %+v", errCodeUnauthorized, res.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestJwksKeySource_ServesCachedKeysWhileFetching(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwks := jwksServer(t, "kid-1", &key.PublicKey)
+	fetching, release := make(chan struct{}, 1), make(chan struct{})
+	var blocking atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocking.Load() {
+			fetching <- struct{}{}
+			<-release
+		}
+		jwks.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	source := newJwksKeySource(srv.URL)
+	if _, err := source.key("kid-1"); err != nil {
+		t.Fatalf("initial fetch error: %v", err)
+	}
+
+	// 캐시가 만료되어 다시 받는 동안에도 알고 있는 키는 기다리지 않고 사용한다.
+	blocking.Store(true)
+	source.mu.Lock()
+	source.fetchedAt = time.Now().Add(-2 * jwksCacheTTL)
+	source.mu.Unlock()
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := source.key("kid-1")
+		refreshed <- err
+	}()
+	<-fetching
+
+	cached := make(chan error, 1)
+	go func() {
+		_, err := source.key("kid-1")
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		if err != nil {
+			t.Fatalf("cached key error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected cached key while JWKS is being fetched")
+	}
+
+	close(release)
+	if err := <-refreshed; err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+}
+
+func TestAuthMiddleware_PublicPaths(t *testing.T) {
+	withAuthConfig(t, "http://127.0.0.1:0/certs", "")
+
+	for _, path := range []string{"/actuator/health", "/actuator/health/liveness", "/actuator/health/readiness", "/v1/migration/encryption/jwks.json"} {
+		if w := serveWithToken(path, ""); w.Code == http.StatusUnauthorized {
+			t.Fatalf("%s should not require a token", path)
+		}
+	}
+	for _, path := range []string{"/actuator/healthz", "/metricsX", "/swagger-admin", "/v1/migration/encryption/jwks.json.bak"} {
+		if isPublicPath(path) {
+			t.Fatalf("%s should require a token", path)
+		}
+	}
+	for _, path := range []string{"/swagger/index.html", "/metrics"} {
+		if w := serveWithToken(path, ""); w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s should require a token by default, got %d", path, w.Code)
+		}
+	}
+
+	config.Env.AuthPublicSwagger = true
+	config.Env.AuthPublicMetrics = true
+	if !isPublicPath("/swagger/index.html") || !isPublicPath("/metrics") {
+		t.Fatal("expected swagger and metrics to be public when enabled")
+	}
+	if isPublicPath("/metricsX") || isPublicPath("/swagger-admin") {
+		t.Fatal("expected only the enabled paths to be public")
+	}
+}
+
+func TestAuthMiddleware_MisconfiguredFailsClosed(t *testing.T) {
+	withAuthConfig(t, "", filepath.Join(t.TempDir(), "missing.pem"))
+
+	if w := serveWithToken("/v1/migration/jobs/missing", "anything"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_NotConfiguredFailsClosed(t *testing.T) {
+	withAuthConfig(t, "", "")
+
+	if w := serveWithToken("/v1/migration/jobs/missing", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without auth settings, got %d", w.Code)
+	}
+	if w := serveWithToken("/actuator/health/liveness", ""); w.Code == http.StatusUnauthorized {
+		t.Fatal("probes should not require a token")
+	}
+
+	config.Env.AuthDisabled = true
+	if w := serveWithToken("/v1/migration/jobs/missing", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with AuthDisabled, got %d", w.Code)
+	}
+}
+
+func TestTokenVerifier_StaticKeyAndIdentity(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "oidc.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	withAuthConfig(t, "", keyFile)
+
+	v, err := newTokenVerifier()
+	if err != nil || v == nil {
+		t.Fatalf("newTokenVerifier error: %v", err)
+	}
+	c, err := v.verify(signTestToken(t, key, "", validClaims()))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	if c.Subject != "user-1" || c.Username != "alice" {
+		t.Fatalf("unexpected caller: %+v", c)
+	}
+	if len(c.Roles) != 2 || c.Roles[0] != "cp-user" || c.Roles[1] != "migrator" {
+		t.Fatalf("expected realm and client roles, got %v", c.Roles)
+	}
+
+	// HS256은 공개키를 HMAC 키로 쓰는 공격을 막기 위해 거부한다.
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	signed, _ := hs.SignedString(der)
+	if _, err := v.verify(signed); err == nil {
+		t.Fatal("expected HS256 token to be rejected")
+	}
+}
+
+func TestAuthMiddleware_PutsCallerInContext(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	v := &tokenVerifier{
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}), jwt.WithExpirationRequired()),
+		keys:   func(string) (crypto.PublicKey, error) { return &key.PublicKey, nil },
+	}
+
+	var got *caller
+	h := authMiddleware(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = callerFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/x", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, "", validClaims()))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got == nil || got.Subject != "user-1" {
+		t.Fatalf("expected caller in context, got %+v", got)
+	}
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kps-migration-api/model"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// API 권한
+const (
+	permBucketList = "bucket:list"
+	permCopy       = "migration:copy"
+	// dst에만 없는 파일을 삭제하는 sync
+	permSync       = "migration:sync"
+	permJobRead    = "job:read"
+	permJobControl = "job:control"
+	// 다른 사용자의 job 조회, 취소, 일시정지, 재개
+	permJobAdmin = "job:admin"
+	permAll      = "*"
+)
+
+var knownPermissions = map[string]bool{
+	permBucketList: true, permCopy: true, permSync: true,
+	permJobRead: true, permJobControl: true, permJobAdmin: true, permAll: true,
+}
+
+// NewHandler에 등록한 API별 필요 권한 ("METHOD path template").
+// 여기에 없는 경로(probe, swagger, jwks)는 권한을 확인하지 않는다.
+var routePermissions = map[string]string{
+	"POST /v1/migration/sync/copy":        permCopy,
+	"POST /v1/migration/sync/sync":        permSync,
+	"POST /v1/migration/operations/list":  permBucketList,
+	"GET /v1/migration/jobs/{id}":         permJobRead,
+	"GET /v1/migration/jobs/{id}/events":  permJobRead,
+	"DELETE /v1/migration/jobs/{id}":      permJobControl,
+	"POST /v1/migration/jobs/{id}/pause":  permJobControl,
+	"POST /v1/migration/jobs/{id}/resume": permJobControl,
+}
+
+// 거부되면 감사 로그에 남기는 마이그레이션 API
+var auditOperations = map[string]string{
+	permSync:       "sync",
+	permCopy:       "copy",
+	permBucketList: "list",
+}
+
+// RBAC 정책 파일 (YAML 또는 JSON)
+//
+//	roles:
+//	  cp-admin:
+//	    permissions: ["*"]
+//	  migration-user:
+//	    permissions: [bucket:list, migration:copy, job:read, job:control]
+//	    storageEndpoints: ["*.kpaas.example.com", "10.0.0.*"]
+//	groups:
+//	  /platform-ops:
+//	    permissions: [migration:sync]
+//
+// roles는 token의 realm/client role, groups는 groups claim과 비교한다.
+// storageEndpoints가 있으면 그 규칙의 권한은 호스트가 패턴(path.Match)에 맞는 스토리지에만 쓸 수 있다.
+type rbacPolicy struct {
+	Roles  map[string]policyRule `yaml:"roles"`
+	Groups map[string]policyRule `yaml:"groups"`
+	// 설정 오류. 설정이 잘못되면 모든 요청을 거부한다.
+	err error
+}
+
+type policyRule struct {
+	Permissions      []string `yaml:"permissions"`
+	StorageEndpoints []string `yaml:"storageEndpoints"`
+}
+
+// 권한이 없어 거부된 요청. reason은 응답 message로 전달한다.
+type accessDenied struct {
+	permission string
+	reason     string
+}
+
+func (e *accessDenied) Error() string {
+	return e.reason
+}
+
+type rbacContextKey struct{}
+
+// 정책 파일을 읽는다. file이 비어 있으면 nil을 반환하고 권한을 확인하지 않는다.
+func loadRbacPolicy(file string) (*rbacPolicy, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy rbacPolicy
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	for _, rules := range []map[string]policyRule{policy.Roles, policy.Groups} {
+		for name, rule := range rules {
+			for _, permission := range rule.Permissions {
+				if !knownPermissions[permission] {
+					return nil, fmt.Errorf("%s: %s: unknown permission %q", file, name, permission)
+				}
+			}
+			for _, pattern := range rule.StorageEndpoints {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("%s: %s: invalid storage endpoint pattern %q", file, name, pattern)
+				}
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// 호출자의 role, group에 해당하는 규칙
+func (p *rbacPolicy) rules(c *caller) []policyRule {
+	var rules []policyRule
+	for _, role := range c.Roles {
+		if rule, ok := p.Roles[role]; ok {
+			rules = append(rules, rule)
+		}
+	}
+	for _, group := range c.Groups {
+		if rule, ok := p.Groups[group]; ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// permission을 허용하는 규칙이 있는지 확인한다. endpoints가 있으면 모두 규칙의 storageEndpoints에 맞아야 한다.
+func (p *rbacPolicy) authorize(c *caller, permission string, endpoints ...string) error {
+	if p.err != nil {
+		return &accessDenied{permission, p.err.Error()}
+	}
+	if c == nil {
+		return &accessDenied{permission, "caller is not authenticated; authorization requires a bearer token"}
+	}
+
+	granted := false
+	for _, rule := range p.rules(c) {
+		if !rule.grants(permission) {
+			continue
+		}
+		granted = true
+		if rule.allowsEndpoints(endpoints) {
+			return nil
+		}
+	}
+	if !granted {
+		return &accessDenied{permission, fmt.Sprintf("none of your roles or groups grants %s", permission)}
+	}
+	return &accessDenied{permission, fmt.Sprintf("storage endpoint %s is not allowed for %s", strings.Join(endpoints, ", "), permission)}
+}
+
+func (r policyRule) grants(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission || p == permAll {
+			return true
+		}
+	}
+	return false
+}
+
+func (r policyRule) allowsEndpoints(endpoints []string) bool {
+	if len(r.StorageEndpoints) == 0 {
+		return true
+	}
+	for _, endpoint := range endpoints {
+		allowed := false
+		for _, pattern := range r.StorageEndpoints {
+			if ok, _ := path.Match(pattern, endpoint); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// API별 권한을 확인하고 이후 스토리지, job 확인에 쓸 정책을 context에 넣는다.
+// authMiddleware 다음에 등록해야 한다.
+func rbacMiddleware(p *rbacPolicy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(wr, r)
+				return
+			}
+			template, _ := route.GetPathTemplate()
+			permission, ok := routePermissions[r.Method+" "+template]
+			if !ok {
+				next.ServeHTTP(wr, r)
+				return
+			}
+
+			c, _ := callerFromContext(r.Context())
+			if err := p.authorize(c, permission); err != nil {
+				writeForbidden(wr, err)
+				if operation, ok := auditOperations[permission]; ok {
+					auditRejected(newAuditRecord(r, operation), errCodeForbidden, err.Error())
+				}
+				return
+			}
+			next.ServeHTTP(wr, r.WithContext(context.WithValue(r.Context(), rbacContextKey{}, p)))
+		})
+	}
+}
+
+// 요청한 스토리지의 endpoint가 permission을 허용하는 규칙에 맞는지 확인한다.
+func authorizeStorage(r *http.Request, permission string, storages ...model.StorageConfig) error {
+	p, ok := r.Context().Value(rbacContextKey{}).(*rbacPolicy)
+	if !ok {
+		return nil
+	}
+	endpoints := make([]string, 0, len(storages))
+	for _, storage := range storages {
+		endpoints = append(endpoints, storageEndpoint(storage))
+	}
+	c, _ := callerFromContext(r.Context())
+	return p.authorize(c, permission, endpoints...)
+}
+
+// 다른 사용자의 job은 job:admin 권한이 있어야 다룰 수 있다.
+func authorizeJob(r *http.Request, j *job) error {
+	p, ok := r.Context().Value(rbacContextKey{}).(*rbacPolicy)
+	if !ok {
+		return nil
+	}
+	c, _ := callerFromContext(r.Context())
+	if c != nil && j.snapshot().Owner == c.Subject {
+		return nil
+	}
+	if err := p.authorize(c, permJobAdmin); err != nil {
+		return &accessDenied{permJobAdmin, "job belongs to another user; " + permJobAdmin + " is required"}
+	}
+	return nil
+}
+
+func writeForbidden(wr http.ResponseWriter, err error) {
+	var denied *accessDenied
+	if !errors.As(err, &denied) {
+		writeError(wr, http.StatusForbidden, errCodeForbidden, err.Error(), nil)
+		return
+	}
+	writeError(wr, http.StatusForbidden, errCodeForbidden, denied.reason, map[string]interface{}{"permission": denied.permission})
+}
+
+// 권한 확인에 사용하는 스토리지 호스트
+func storageEndpoint(storage model.StorageConfig) string {
+	switch strings.ToLower(storage.StorageType) {
+	case storageTypeS3:
+		if storage.Endpoint == "" {
+			return "s3.amazonaws.com"
+		}
+		return endpointHost(storage.Endpoint)
+	case storageTypeAzureBlob:
+		if storage.AzureBlob != nil && storage.AzureBlob.SasUrl != "" {
+			return endpointHost(storage.AzureBlob.SasUrl)
+		}
+		if storage.AzureBlob != nil {
+			return strings.ToLower(storage.AzureBlob.Account) + ".blob.core.windows.net"
+		}
+	case storageTypeGcs, "gcs":
+		return "storage.googleapis.com"
+	case storageTypeSwift:
+		if storage.Swift != nil {
+			return endpointHost(storage.Swift.AuthUrl)
+		}
+	case storageTypeSftp:
+		if storage.Sftp != nil {
+			return endpointHost(storage.Sftp.Host)
+		}
+	case storageTypeLocal:
+		return "local"
+	}
+	return ""
+}
+
+func endpointHost(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		if u, err := url.Parse(endpoint); err == nil {
+			return strings.ToLower(u.Hostname())
+		}
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(strings.TrimSuffix(endpoint, "/"))
+}
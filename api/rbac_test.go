@@ -0,0 +1,214 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+const testPolicy = `
+roles:
+  cp-migration-admin:
+    permissions: ["*"]
+  cp-migration-user:
+    permissions: [bucket:list, migration:copy, job:read, job:control]
+    storageEndpoints: ["*.internal.example.com"]
+groups:
+  /ops:
+    permissions: [migration:copy]
+`
+
+func writePolicy(t *testing.T, policy string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(file, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// 정적 키로 token을 검증하고 testPolicy로 권한을 확인하도록 설정한다.
+func withRbac(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "oidc.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	withAuthConfig(t, "", keyFile)
+	config.Env.RbacPolicyFile = writePolicy(t, testPolicy)
+	return key
+}
+
+func roleToken(t *testing.T, key *rsa.PrivateKey, subject string, roles ...string) string {
+	claims := validClaims()
+	claims["sub"] = subject
+	claims["realm_access"] = map[string]interface{}{"roles": roles}
+	claims["resource_access"] = map[string]interface{}{}
+	return signTestToken(t, key, "", claims)
+}
+
+func TestLoadRbacPolicy_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown permission": "roles:\n  a:\n    permissions: [migration:delete]\n",
+		"unknown field":      "roles:\n  a:\n    permission: [job:read]\n",
+		"bad pattern":        "roles:\n  a:\n    permissions: [job:read]\n    storageEndpoints: [\"[\"]\n",
+	}
+	for name, policy := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := loadRbacPolicy(writePolicy(t, policy)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+	if p, err := loadRbacPolicy(""); p != nil || err != nil {
+		t.Fatalf("expected no policy, got %v, %v", p, err)
+	}
+}
+
+func TestRbacPolicy_Authorize(t *testing.T) {
+	p, err := loadRbacPolicy(writePolicy(t, testPolicy))
+	if err != nil {
+		t.Fatalf("loadRbacPolicy error: %v", err)
+	}
+	user := &caller{Subject: "u", Roles: []string{"cp-migration-user"}}
+	ops := &caller{Subject: "o", Roles: []string{"cp-migration-user"}, Groups: []string{"/ops"}}
+	admin := &caller{Subject: "a", Roles: []string{"cp-migration-admin"}}
+
+	cases := []struct {
+		name       string
+		caller     *caller
+		permission string
+		endpoints  []string
+		allowed    bool
+	}{
+		{"role grants", user, permCopy, nil, true},
+		{"role does not grant", user, permSync, nil, false},
+		{"endpoint allowed", user, permCopy, []string{"s3.internal.example.com", "ceph.internal.example.com"}, true},
+		{"endpoint denied", user, permCopy, []string{"s3.internal.example.com", "s3.amazonaws.com"}, false},
+		{"unrestricted group rule", ops, permCopy, []string{"s3.amazonaws.com"}, true},
+		{"wildcard", admin, permJobAdmin, []string{"anything"}, true},
+		{"no caller", nil, permJobRead, nil, false},
+		{"no matching role", &caller{Subject: "x", Roles: []string{"other"}}, permJobRead, nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.authorize(tc.caller, tc.permission, tc.endpoints...)
+			if (err == nil) != tc.allowed {
+				t.Fatalf("expected allowed=%v, got %v", tc.allowed, err)
+			}
+			var denied *accessDenied
+			if err != nil && (!errors.As(err, &denied) || denied.reason == "") {
+				t.Fatalf("expected a reason, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStorageEndpoint(t *testing.T) {
+	cases := []struct {
+		storage model.StorageConfig
+		want    string
+	}{
+		{model.StorageConfig{StorageType: "s3", Endpoint: "https://S3.Internal.example.com:9000/"}, "s3.internal.example.com"},
+		{model.StorageConfig{StorageType: "S3", Endpoint: "10.0.0.5:7480"}, "10.0.0.5"},
+		{model.StorageConfig{StorageType: "s3"}, "s3.amazonaws.com"},
+		{model.StorageConfig{StorageType: "azureblob", AzureBlob: &model.AzureBlobConfig{Account: "acct", Key: "k"}}, "acct.blob.core.windows.net"},
+		{model.StorageConfig{StorageType: "gcs"}, "storage.googleapis.com"},
+		{model.StorageConfig{StorageType: "swift", Swift: &model.SwiftConfig{AuthUrl: "https://keystone.example.com/v3"}}, "keystone.example.com"},
+		{model.StorageConfig{StorageType: "sftp", Sftp: &model.SftpConfig{Host: "files.example.com"}}, "files.example.com"},
+		{model.StorageConfig{StorageType: "local"}, "local"},
+	}
+	for _, tc := range cases {
+		if got := storageEndpoint(tc.storage); got != tc.want {
+			t.Errorf("%s %q: expected %q, got %q", tc.storage.StorageType, tc.storage.Endpoint, tc.want, got)
+		}
+	}
+}
+
+func TestRbac_DeniesRouteAndStorage(t *testing.T) {
+	key := withRbac(t)
+	user := roleToken(t, key, "user-1", "cp-migration-user")
+
+	cases := []struct {
+		name       string
+		path       string
+		body       string
+		permission string
+	}{
+		{"sync not granted", "/v1/migration/sync/sync", `{}`, permSync},
+		{"copy to other endpoint", "/v1/migration/sync/copy",
+			`{"src":{"storageType":"s3","endpoint":"https://s3.internal.example.com","bucket":"a"},"dst":{"storageType":"s3","endpoint":"https://s3.amazonaws.com","bucket":"b"}}`, permCopy},
+		{"list other endpoint", "/v1/migration/operations/list", `{"storageType":"s3","endpoint":"https://evil.example.com"}`, permBucketList},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Authorization", "Bearer "+user)
+			w := httptest.NewRecorder()
+			NewHandler().ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+			}
+			res := decodeResponse(t, w.Body, nil)
+			if res.Error == nil || res.Error.Code != errCodeForbidden || res.Error.Details["permission"] != tc.permission {
+				t.Fatalf("unexpected error: %+v", res.Error)
+			}
+			if !strings.Contains(res.Error.Message, tc.permission) {
+				t.Fatalf("expected reason to name %s, got %q", tc.permission, res.Error.Message)
+			}
+		})
+	}
+}
+
+func TestRbac_JobOwnership(t *testing.T) {
+	key := withRbac(t)
+	j := newRunningJob(jobs, "copy", "sync/copy", model.SyncRequest{})
+	j.setOwner("owner-1")
+	t.Cleanup(func() { jobs.remove(j.id) })
+
+	cases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"owner", roleToken(t, key, "owner-1", "cp-migration-user"), http.StatusOK},
+		{"other user", roleToken(t, key, "user-2", "cp-migration-user"), http.StatusForbidden},
+		{"admin", roleToken(t, key, "admin-1", "cp-migration-admin"), http.StatusOK},
+		{"no role", roleToken(t, key, "owner-1"), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := serveWithToken("/v1/migration/jobs/"+j.id, tc.token); w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRbac_MisconfiguredFailsClosed(t *testing.T) {
+	key := withRbac(t)
+	config.Env.RbacPolicyFile = filepath.Join(t.TempDir(), "missing.yaml")
+
+	w := serveWithToken("/v1/migration/jobs/missing", roleToken(t, key, "admin-1", "cp-migration-admin"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+	if w := serveWithToken("/actuator/health/liveness", ""); w.Code == http.StatusForbidden {
+		t.Fatal("probes should not require authorization")
+	}
+}
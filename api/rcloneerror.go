@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// rclone과 backend 오류 메시지를 API 오류 코드로 분류하는 규칙. 위에서부터 먼저 맞는 규칙을 사용한다.
+// 메시지는 소문자로 바꿔 비교한다.
+var rcloneErrorClasses = []struct {
+	code     string
+	status   int
+	patterns []string
+}{
+	{errCodeTlsError, http.StatusBadGateway, []string{
+		"x509:", "tls:", "certificate", "first record does not look like a tls handshake",
+	}},
+	{errCodeAuthFailed, http.StatusUnauthorized, []string{
+		"invalidaccesskeyid", "signaturedoesnotmatch", "authenticationfailed", "authorizationheadermalformed",
+		"expiredtoken", "invalidtoken", "invalid_grant", "unable to authenticate", "authentication failed",
+		"unauthorized", "status code: 401", "response 401",
+	}},
+	{errCodePermissionDenied, http.StatusForbidden, []string{
+		"accessdenied", "access denied", "authorizationpermissionmismatch", "authorizationfailure",
+		"permission denied", "forbidden", "status code: 403", "response 403",
+	}},
+	{errCodeBucketNotFound, http.StatusNotFound, []string{
+		"nosuchbucket", "containernotfound", "bucket not found", "container not found",
+		"directory not found", "no such file or directory", "status code: 404", "response 404",
+	}},
+	{errCodeQuotaExceeded, http.StatusInsufficientStorage, []string{
+		"quotaexceeded", "quota exceeded", "storagequotaexceeded", "insufficient storage",
+		"no space left on device", "entitytoolarge",
+	}},
+	{errCodeRateLimited, http.StatusTooManyRequests, []string{
+		"slowdown", "toomanyrequests", "too many requests", "ratelimitexceeded", "rate limit",
+	}},
+	{errCodeEndpointUnreachable, http.StatusBadGateway, []string{
+		"connection refused", "no such host", "i/o timeout", "network is unreachable", "no route to host",
+		"connection reset by peer", "context deadline exceeded", "client.timeout exceeded",
+		"unsupported protocol scheme", "server misbehaving",
+	}},
+}
+
+// rclone 오류 메시지에 맞는 http status와 오류 코드를 반환한다.
+// 분류할 수 없으면 rclone이 반환한 status와 RCLONE_ERROR를 사용한다.
+func classifyRcloneError(message string, status int) (int, string) {
+	lower := strings.ToLower(message)
+	for _, class := range rcloneErrorClasses {
+		for _, pattern := range class.patterns {
+			if strings.Contains(lower, pattern) {
+				return class.status, class.code
+			}
+		}
+	}
+	if status < http.StatusBadRequest {
+		status = http.StatusInternalServerError
+	}
+	return status, errCodeRcloneError
+}
+
+// rclone rc 오류 응답({"error": "...", "status": ...})에서 오류 메시지를 꺼낸다.
+func rcloneErrorMessage(out string, status int) string {
+	var result struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil || result.Error == "" {
+		return fmt.Sprintf("rclone returned status %d", status)
+	}
+	return result.Error
+}
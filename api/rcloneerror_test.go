@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+func TestClassifyRcloneError(t *testing.T) {
+	tests := []struct {
+		message    string
+		wantStatus int
+		wantCode   string
+	}{
+		{"InvalidAccessKeyId: The AWS Access Key Id you provided does not exist in our records.\n\tstatus code: 403", http.StatusUnauthorized, errCodeAuthFailed},
+		{"SignatureDoesNotMatch: The request signature we calculated does not match the signature you provided.", http.StatusUnauthorized, errCodeAuthFailed},
+		{"ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password]", http.StatusUnauthorized, errCodeAuthFailed},
+		{"AccessDenied: Access Denied\n\tstatus code: 403, request id: 1", http.StatusForbidden, errCodePermissionDenied},
+		{"RESPONSE 403: This request is not authorized to perform this operation using this permission. AuthorizationPermissionMismatch", http.StatusForbidden, errCodePermissionDenied},
+		{"NoSuchBucket: The specified bucket does not exist\n\tstatus code: 404", http.StatusNotFound, errCodeBucketNotFound},
+		{"directory not found", http.StatusNotFound, errCodeBucketNotFound},
+		{"QuotaExceeded: The quota has been exceeded", http.StatusInsufficientStorage, errCodeQuotaExceeded},
+		{"SlowDown: Please reduce your request rate.", http.StatusTooManyRequests, errCodeRateLimited},
+		{`Get "https://minio:9000/": tls: failed to verify certificate: x509: certificate signed by unknown authority`, http.StatusBadGateway, errCodeTlsError},
+		{`Get "http://minio:9000/": dial tcp 10.0.0.1:9000: connect: connection refused`, http.StatusBadGateway, errCodeEndpointUnreachable},
+		{`dial tcp: lookup nowhere.invalid: no such host`, http.StatusBadGateway, errCodeEndpointUnreachable},
+		{"something unexpected", http.StatusInternalServerError, errCodeRcloneError},
+	}
+	for _, tt := range tests {
+		status, code := classifyRcloneError(tt.message, http.StatusInternalServerError)
+		if status != tt.wantStatus || code != tt.wantCode {
+			t.Errorf("classifyRcloneError(%q): expected %d %s, got %d %s", tt.message, tt.wantStatus, tt.wantCode, status, code)
+		}
+	}
+}
+
+func TestRcloneErrorMessage_MissingError(t *testing.T) {
+	tests := []string{``, `not-json`, `{"status":500}`, `{"error":123}`}
+	for _, out := range tests {
+		if got := rcloneErrorMessage(out, 500); got != "rclone returned status 500" {
+			t.Errorf("rcloneErrorMessage(%q): unexpected %q", out, got)
+		}
+	}
+}
+
+func TestBucketList_RcloneErrorWithoutMessage_DoesNotPanic(t *testing.T) {
+	if config.Env != nil {
+		config.Env.IsEncryption = "false"
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		setStatus(http.StatusInternalServerError)
+		setOut(`{"status":500}`)
+
+		body, _ := json.Marshal(model.StorageConfig{StorageType: "s3"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		bucketList(w, req)
+
+		response := decodeResponse(t, w.Body, nil)
+		if w.Result().StatusCode != http.StatusInternalServerError || response.Error == nil || response.Error.Code != errCodeRcloneError {
+			t.Fatalf("expected 500 %s, got %d %+v", errCodeRcloneError, w.Result().StatusCode, response)
+		}
+	})
+}
+
+func TestJobFinish_SetsErrorCode(t *testing.T) {
+	j := newRunningJob(jobs, "sync", "sync/sync", model.SyncRequest{})
+	defer jobs.remove(j.id)
+
+	j.finish(0, model.RcloneJobStatus{EndTime: time.Now(), Error: "NoSuchBucket: The specified bucket does not exist"})
+
+	status := j.snapshot()
+	if status.State != model.JobStateError || status.ErrorCode != errCodeBucketNotFound {
+		t.Fatalf("expected error state with %s, got %+v", errCodeBucketNotFound, status)
+	}
+}
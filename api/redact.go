@@ -0,0 +1,97 @@
+package api
+
+import (
+	"kps-migration-api/config"
+	"regexp"
+	"strings"
+
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// connection string 옵션 중 인증 정보가 담기는 옵션
+var secretOptions = map[string]bool{
+	"access_key_id":               true,
+	"secret_access_key":           true,
+	"key":                         true,
+	"sas_url":                     true,
+	"service_account_credentials": true,
+	"pass":                        true,
+	"key_pem":                     true,
+}
+
+const redacted = "***"
+
+// rclone 오류 메시지에 포함된 connection string 옵션(key="value" 또는 key=value)
+// JSON 문자열 안에서는 따옴표가 \"로, 값 안의 ""는 \"\"로 escape 된다.
+var optionPattern = regexp.MustCompile(`([a-z_]+)=(\\"(?:[^"\\]|\\\\|\\"\\")*\\"|"(?:[^"]|"")*"|[^,:"\\\s]*)`)
+
+// 오류 메시지에서 인증 정보를 가린다.
+// fsStrings는 요청에 사용한 connection string으로, 그 안의 인증 정보 값은 메시지 어디에 있든 가린다.
+func redactSecrets(message string, fsStrings ...string) string {
+	for _, fsString := range fsStrings {
+		parsed, err := fspath.Parse(fsString)
+		if err != nil {
+			continue
+		}
+		for key, value := range parsed.Config {
+			if secretOptions[key] && value != "" {
+				message = strings.ReplaceAll(message, value, redacted)
+			}
+		}
+	}
+
+	return optionPattern.ReplaceAllStringFunc(message, func(option string) string {
+		key := option[:strings.Index(option, "=")]
+		if !secretOptions[key] {
+			return option
+		}
+		return key + `="` + redacted + `"`
+	})
+}
+
+// 로그 속성, JSON 필드 중 값 전체를 가리는 이름 (소문자, _와 - 제외)
+var secretLogKeys = map[string]bool{
+	"accesskeyid":               true,
+	"secretaccesskey":           true,
+	"key":                       true,
+	"sasurl":                    true,
+	"serviceaccountcredentials": true,
+	"pass":                      true,
+	"password":                  true,
+	"keypem":                    true,
+	"hmac":                      true,
+	"hmacdata":                  true,
+	"hmackey":                   true,
+	"privatekey":                true,
+	"authorization":             true,
+	"token":                     true,
+}
+
+func normalizeLogKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
+
+var (
+	// PEM 개인키
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(-----END [A-Z ]*PRIVATE KEY-----|$)`)
+	// 문자열로 기록된 JSON의 인증 정보 필드 ("secretAccessKey":"...")
+	jsonSecretPattern = regexp.MustCompile(`(?i)"(accessKeyId|secretAccessKey|key|sasUrl|serviceAccountCredentials|pass|password|keyPem|hmac_data|hmacKey|privateKey|token)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+	bearerPattern     = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`)
+	// AWS access key ID
+	accessKeyIdPattern = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+)
+
+// 로그 메시지에서 인증 정보를 가린다.
+// 설정의 HMAC 키, 개인키 값과 개인키 PEM, JSON 필드, bearer token, connection string 옵션이 대상이다.
+func redactLog(message string) string {
+	for _, secret := range []string{config.Env.HmacKey, config.Env.PrivateKey} {
+		if secret != "" {
+			message = strings.ReplaceAll(message, secret, redacted)
+		}
+	}
+	message = privateKeyPattern.ReplaceAllString(message, redacted)
+	message = jsonSecretPattern.ReplaceAllString(message, `"$1":"`+redacted+`"`)
+	message = bearerPattern.ReplaceAllString(message, "Bearer "+redacted)
+	message = accessKeyIdPattern.ReplaceAllString(message, redacted)
+	return redactSecrets(message)
+}
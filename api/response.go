@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+)
+
+// 응답 error.code
+const (
+	errCodeInvalidRequest  = "INVALID_REQUEST"
+	errCodeInvalidFilter   = "INVALID_FILTER"
+	errCodeInvalidStorage  = "INVALID_STORAGE"
+	errCodeInvalidTuning   = "INVALID_TUNING"
+	errCodeRcloneError     = "RCLONE_ERROR"
+	errCodeJobNotFound     = "JOB_NOT_FOUND"
+	errCodeJobNotActive    = "JOB_NOT_ACTIVE"
+	errCodeInternal        = "INTERNAL_ERROR"
+	errCodeUnauthorized    = "UNAUTHORIZED"
+	errCodeForbidden       = "FORBIDDEN"
+	errCodeRequestTooLarge = "REQUEST_TOO_LARGE"
+	errCodeRequestExpired  = "REQUEST_EXPIRED"
+	errCodeReplayedRequest = "REPLAYED_REQUEST"
+	errCodeShuttingDown    = "SHUTTING_DOWN"
+	errCodeQueueFull       = "QUEUE_FULL"
+	errCodeUserJobLimit    = "USER_JOB_LIMIT"
+
+	// rclone, backend 오류 분류 (rcloneerror.go)
+	errCodeAuthFailed          = "AUTH_FAILED"
+	errCodePermissionDenied    = "PERMISSION_DENIED"
+	errCodeBucketNotFound      = "BUCKET_NOT_FOUND"
+	errCodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	errCodeRateLimited         = "RATE_LIMITED"
+	errCodeTlsError            = "TLS_ERROR"
+	errCodeEndpointUnreachable = "ENDPOINT_UNREACHABLE"
+)
+
+// 요청의 AES 키로 응답을 암호화하는 ResponseWriter.
+// 암호화된 요청 본문이 있는 API(sync, copy, bucketList)에만 사용할 수 있다.
+type encryptedResponseWriter struct {
+	http.ResponseWriter
+	key *payloadKey
+}
+
+// 응답 암호화가 켜져 있으면 이후 응답을 요청의 키와 alg로 암호화한다.
+func withResponseEncryption(wr http.ResponseWriter, key *payloadKey) http.ResponseWriter {
+	if config.Env.IsResponseEncryption != "true" || key == nil {
+		return wr
+	}
+	return &encryptedResponseWriter{ResponseWriter: wr, key: key}
+}
+
+func writeResponse(wr http.ResponseWriter, status int, response model.Response) {
+	var body interface{} = response
+	if ew, ok := wr.(*encryptedResponseWriter); ok {
+		encrypted, err := encodePayload(response, ew.key)
+		if err != nil {
+			status = http.StatusInternalServerError
+			body = model.Response{
+				Status: model.ResponseStatusError,
+				Error:  &model.ResponseError{Code: errCodeInternal, Message: "response encryption failed"},
+			}
+		} else {
+			body = encrypted
+		}
+	}
+
+	wr.Header().Set("Content-type", "application/json")
+	wr.WriteHeader(status)
+	json.NewEncoder(wr).Encode(body)
+}
+
+func writeData(wr http.ResponseWriter, status int, data interface{}) {
+	writeResponse(wr, status, model.Response{Status: model.ResponseStatusSuccess, Data: data})
+}
+
+// 오류 메시지에 포함된 인증 정보는 redactSecrets로 가린 뒤 전달해야 한다.
+func writeError(wr http.ResponseWriter, status int, code string, message string, details map[string]interface{}) {
+	writeResponse(wr, status, model.Response{
+		Status: model.ResponseStatusError,
+		Error:  &model.ResponseError{Code: code, Message: message, Details: details},
+	})
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+// 응답 envelope을 읽고 data를 v로 디코딩한다.
+func decodeResponse(t *testing.T, body io.Reader, v interface{}) model.Response {
+	t.Helper()
+
+	var response struct {
+		model.Response
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if v != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, v); err != nil {
+			t.Fatalf("decode response data: %v", err)
+		}
+	}
+	return response.Response
+}
+
+func TestBucketList_Success_ReturnsJSONEnvelope(t *testing.T) {
+	if config.Env != nil {
+		config.Env.IsEncryption = "false"
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		setOut(`{"list":[{"Name":"bucket1","IsDir":true}]}`)
+
+		body, _ := json.Marshal(model.StorageConfig{StorageType: "s3", AccessKeyId: "key", SecretAccessKey: "secret"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		bucketList(w, req)
+
+		if ct := w.Result().Header.Get("Content-type"); ct != "application/json" {
+			t.Fatalf("expected application/json, got %q", ct)
+		}
+		var data struct {
+			List []struct{ Name string } `json:"list"`
+		}
+		response := decodeResponse(t, w.Body, &data)
+		if response.Status != model.ResponseStatusSuccess || response.Error != nil {
+			t.Fatalf("expected success envelope, got %+v", response)
+		}
+		if len(data.List) != 1 || data.List[0].Name != "bucket1" {
+			t.Fatalf("expected bucket list in data, got %+v", data)
+		}
+	})
+}
+
+func TestBucketList_RcloneError_KeepsRedactedMessage(t *testing.T) {
+	if config.Env != nil {
+		config.Env.IsEncryption = "false"
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		setStatus(http.StatusInternalServerError)
+		setOut(`{"error":"failed to create file system for \":s3,access_key_id=\\\"AKIAEXAMPLE\\\",secret_access_key=\\\"topsecret\\\":\": SignatureDoesNotMatch (secret topsecret)","status":500}`)
+
+		body, _ := json.Marshal(model.StorageConfig{StorageType: "s3", AccessKeyId: "AKIAEXAMPLE", SecretAccessKey: "topsecret"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		bucketList(w, req)
+
+		if w.Result().StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Result().StatusCode)
+		}
+		response := decodeResponse(t, w.Body, nil)
+		if response.Status != model.ResponseStatusError || response.Error == nil || response.Error.Code != errCodeAuthFailed {
+			t.Fatalf("expected %s error envelope, got %+v", errCodeAuthFailed, response)
+		}
+		message := response.Error.Message
+		if !strings.Contains(message, "SignatureDoesNotMatch") {
+			t.Fatalf("expected rclone message to be kept, got %q", message)
+		}
+		if strings.Contains(message, "topsecret") || strings.Contains(message, "AKIAEXAMPLE") {
+			t.Fatalf("expected credentials to be redacted, got %q", message)
+		}
+	})
+}
+
+func TestJobStatus_NotFound_ReturnsErrorCode(t *testing.T) {
+	withAuthDisabled(t)
+	req := httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/none", nil)
+	w := httptest.NewRecorder()
+
+	NewHandler().ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Result().StatusCode)
+	}
+	response := decodeResponse(t, w.Body, nil)
+	if response.Error == nil || response.Error.Code != errCodeJobNotFound {
+		t.Fatalf("expected %s, got %+v", errCodeJobNotFound, response)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	fsString := `:sftp,host="10.0.0.1",user="u",pass="b2JzY3VyZWQ":/home`
+	tests := []struct {
+		message   string
+		fsStrings []string
+		want      string
+	}{
+		{`bad key=abc,endpoint=x`, nil, `bad key="***",endpoint=x`},
+		{`secret_access_key="a,""b"" c":`, nil, `secret_access_key="***":`},
+		{`account="acct",sas_url="https://x?sig=1"`, nil, `account="acct",sas_url="***"`},
+		{`ssh: handshake failed for pass b2JzY3VyZWQ`, []string{fsString}, `ssh: handshake failed for pass ***`},
+		{`dial tcp 10.0.0.1:22: connection refused`, []string{fsString}, `dial tcp 10.0.0.1:22: connection refused`},
+	}
+	for _, tt := range tests {
+		if got := redactSecrets(tt.message, tt.fsStrings...); got != tt.want {
+			t.Errorf("redactSecrets(%q): expected %q, got %q", tt.message, tt.want, got)
+		}
+	}
+}
+
+func TestBucketList_ResponseEncryption(t *testing.T) {
+	if config.Env == nil {
+		t.Skip("config.Env is nil")
+	}
+	oldEncryption, oldResponseEncryption := config.Env.IsEncryption, config.Env.IsResponseEncryption
+	defer func() {
+		config.Env.IsEncryption, config.Env.IsResponseEncryption = oldEncryption, oldResponseEncryption
+	}()
+	config.Env.IsEncryption = "true"
+	config.Env.IsResponseEncryption = "true"
+
+	key := bytes.Repeat([]byte{7}, 32)
+	oldDecode := decodeStorageConfig
+	defer func() { decodeStorageConfig = oldDecode }()
+	decodeStorageConfig = func(payload model.HybridPayload) (model.StorageConfig, *payloadKey, error) {
+		return model.StorageConfig{StorageType: "s3"}, &payloadKey{alg: model.PayloadAlgAesCbc, aesKey: key}, nil
+	}
+
+	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
+		setOut(`{"list":[{"Name":"secret-bucket"}]}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/migration/operations/list", strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+
+		bucketList(w, req)
+
+		if strings.Contains(w.Body.String(), "secret-bucket") {
+			t.Fatalf("expected encrypted response, got %s", w.Body.String())
+		}
+		var encrypted model.EncryptedPayload
+		if err := json.NewDecoder(w.Body).Decode(&encrypted); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		cipherText, _ := base64.StdEncoding.DecodeString(encrypted.EncryptedData)
+		iv, _ := base64.StdEncoding.DecodeString(encrypted.IV)
+		plainText, err := aesDecrypt(cipherText, key, iv)
+		if err != nil {
+			t.Fatalf("aesDecrypt error: %v", err)
+		}
+		var payload model.PayloadModelWithHMAC[model.Response]
+		json.Unmarshal(plainText, &payload)
+		if payload.Data.Status != model.ResponseStatusSuccess || payload.Hmac_data == "" || !strings.Contains(string(plainText), "secret-bucket") {
+			t.Fatalf("unexpected decrypted response %s", plainText)
+		}
+	})
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/rclone/rclone/librclone/librclone"
 	"kps-migration-api/config"
 	"kps-migration-api/model"
@@ -12,15 +11,16 @@ import (
 
 var (
 	rcloneInitialize = librclone.Initialize
-	rcloneRPC        = librclone.RPC
+	rcloneRPC        = instrumentRcloneRPC(librclone.RPC)
 )
 
-func defaultDecodeSyncConfig(payload model.HybridPayload) (model.SyncConfig, error) {
-	return decodePayload[model.SyncConfig](payload)
+// 요청 객체와 응답 암호화에 사용할 키를 반환한다.
+func defaultDecodeSyncConfig(payload model.HybridPayload) (model.SyncConfig, *payloadKey, error) {
+	return decodeHybridPayload[model.SyncConfig](payload)
 }
 
-func defaultDecodeStorageConfig(payload model.HybridPayload) (model.StorageConfig, error) {
-	return decodePayload[model.StorageConfig](payload)
+func defaultDecodeStorageConfig(payload model.HybridPayload) (model.StorageConfig, *payloadKey, error) {
+	return decodeHybridPayload[model.StorageConfig](payload)
 }
 
 var (
@@ -29,7 +29,16 @@ var (
 )
 
 // @Summary Synchronize between storage
-// @Description Synchronize between storage.
+// @Description Synchronize between storage. Objects missing from src are deleted from dst, and a dry run lists them as planned deletes.
+// @Description The request body is model.SyncConfig. Its fields (storageType, dryRun, filter, priority, tuning) are documented on model.SyncConfig and model.StorageConfig.
+// @Description The job runs in the background. Check its status with /v1/migration/jobs/{id}.
+// @Description Storage failures are reported in error.code as AUTH_FAILED, PERMISSION_DENIED, BUCKET_NOT_FOUND, QUOTA_EXCEEDED, RATE_LIMITED, TLS_ERROR, ENDPOINT_UNREACHABLE or RCLONE_ERROR.
+// @Description Calls denied by the RBAC policy return 403 with error.code FORBIDDEN. Requires the "migration:sync" permission for the storage endpoints.
+// @Description At most MaxConcurrentJobs jobs run at once. Other jobs wait in a queue and report "queuePosition".
+// @Description A full queue or too many active jobs for the user returns 429 with Retry-After and error.code QUEUE_FULL or USER_JOB_LIMIT.
+// @Description Tuning values above the server maximums return 400 with error.code INVALID_TUNING.
+// @Description With response encryption enabled the body is model.EncryptedPayload, encrypted with the AES key of the request.
+// @Description Malformed or undecryptable bodies return 400 with error.code INVALID_REQUEST. An expired encrypted request returns 401 with REQUEST_EXPIRED and a reused nonce returns 409 with REPLAYED_REQUEST.
 // @Description Example request body before encoding :
 // @Description {
 // @Description     "src": {
@@ -44,175 +53,216 @@ var (
 // @Description         "endpoint": "http://url.com",
 // @Description         "accessKeyId": "admin",
 // @Description         "secretAccessKey": "admin",
-// @Description         "bucket": "abcd"
+// @Description         "bucket": "abcd",
+// @Description         "provider": "Ceph",
+// @Description         "region": "kr-central-1",
+// @Description         "forcePathStyle": true
+// @Description     },
+// @Description     "dryRun": false,
+// @Description     "priority": "normal",
+// @Description     "tuning": {
+// @Description         "transfers": 8,
+// @Description         "checkers": 16,
+// @Description         "chunkSize": "64M",
+// @Description         "uploadConcurrency": 4,
+// @Description         "bwLimit": "50M"
+// @Description     },
+// @Description     "filter": {
+// @Description         "exclude": ["*.log", "tmp/**"],
+// @Description         "rules": ["- *.bak", "+ **"],
+// @Description         "minSize": "1k",
+// @Description         "maxSize": "10G",
+// @Description         "minAge": "1h",
+// @Description         "maxAge": "30d"
 // @Description     }
 // @Description }
 // @Tags Migration
 // @Accept json
 // @Produce json
 // @Param payload body model.HybridPayload true "encode base64 model.SyncConfig" SchemaExample({"key":"iCJcdWA198EF1uuzePq8M+Onsx8/4KJ9Mm9YDY+j+KUSxV+/4YyRkeDiuVAyRHkU8UUTyXS/zRhb2t8gUMKJiJaReLFgVyrweVMxRLk2Jx1yshNQ++Y/Cq4+Jvvk9lqsqpbmcoP2VrQoP4C4vymWQ+jwaV/vkJfUK0j6l6LlZenL8PmSzbhzO8kFVaS69TULu4IKbAK9YBeFN/QFhR84/St2fkd5RO8drgN0nPjieVlePc1N9mQewxkPQFXEsDAS3ws3xiBrw7YpHMn7mAk2VZ5y4F3vuEbGL7dIgWbG5UqGXdFGpyUnD91b7Tq+oMCrYhFIquOOIi3eqXirgmdZqOpmdBjDpM97t3oLKpCJDKTU9ceQSAVRSfP4HAN2LSbHTj/n4IZsVzpfcgTuXq0jHIRfrM3VPWnkidpI0v5cWMpJwAGvWcYmR7mwKCeSTJJ5/iE+0usbc/BaUQxFberLf2+QgIsNaR9V9iVnswwMQ0KVGGitsrEuCiTPv5Nlhi/WK8qvw42sFt098EPToWJ7/yHYAEEwt5Wr+4mAh3rNiLFfFB85JWM4ScrHyufHcHU4weqb/Bg5MD2ctO5fL7sp0vSOLSS3ziBpganU8Wzj7rLbaMrJbrhyZuhm6d7XdH/TvROFbTKO5bXHxwOJ77k/AF69c3i3JH6DmmTxH1Kff4w=","iv":"8m8VfEeiyyBf6PTHdcKFVw==","data":"7B/LVElTJlN5YYnYyYaeht9WKr/vZftXx/O65HI/Uqn+02kxkYHKgLdRWJiyo0SQFapqgWKltuOW6Qce/vxOdJeG6yCqqI/UUF254o+YgAeDuI2nh7egDTJZhxN3RuTGJXOwIX54ykWK30AajAJWcTaC6cgGmc418EUauLu2mLXQf0i0s5cou/lZDtmjn0ZqQ35LNvB6BoMcfwSg4TVtW87kqELnAtbErfF22Cx9L1xfyyR7xcbMzrHXejBNGnKehnXGHd1YnH7DC+oOppjYloJ577+d/bcYZiYWmZOWhlOqcgg2rHg8rGgDKuPhyd5LVaydQ+HTOI+1Z7AetZ9szO/xfdUm46HCq6NjjTARD6CjLIUb+9aX1o9XUi7o6Fd1o6d8ATlEO1wRH6KdXK9QBhbyEgw4HL1IryChWK3ETvq9OS77yEo0sNpHLFijK/W+hV93oqaB7DniIFn21IzgxQ=="})
-// @Success 200 {object} string
-// @Failure 500 {object} string
+// @Success 202 {object} model.Response{data=model.JobAccepted}
+// @Failure 400 {object} model.Response
+// @Failure 401 {object} model.Response
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 413 {object} model.Response
+// @Failure 429 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Failure 502 {object} model.Response
+// @Failure 503 {object} model.Response
+// @Failure 507 {object} model.Response
+// @Security BearerAuth
 // @Router /v1/migration/sync/sync [post]
 func sync(wr http.ResponseWriter, r *http.Request) {
-	//wr.Header().Add("Access-Control-Allow-Origin", "*")
-	//wr.Header().Add("Access-Control-Allow-Credentials", "true")
-	//wr.Header().Add("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-	//wr.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	startMigration(wr, r, "sync/sync", "sync")
+}
 
-	var syncConfig model.SyncConfig
-	var err error
-	if config.Env.IsEncryption == "true" {
-		var payload model.HybridPayload
-		_ = json.NewDecoder(r.Body).Decode(&payload)
-		syncConfig, err = decodeSyncConfig(payload)
+// @Summary Copying Between Storage
+// @Description Copying Between Storage. Objects missing from src are kept in dst.
+// @Description The request body, responses and error codes are the same as /v1/migration/sync/sync, except that it requires the "migration:copy" permission for the storage endpoints.
+// @Tags Migration
+// @Accept json
+// @Produce json
+// @Param payload body model.HybridPayload true "encode base64 model.SyncConfig" SchemaExample({"key":"iCJcdWA198EF1uuzePq8M+Onsx8/4KJ9Mm9YDY+j+KUSxV+/4YyRkeDiuVAyRHkU8UUTyXS/zRhb2t8gUMKJiJaReLFgVyrweVMxRLk2Jx1yshNQ++Y/Cq4+Jvvk9lqsqpbmcoP2VrQoP4C4vymWQ+jwaV/vkJfUK0j6l6LlZenL8PmSzbhzO8kFVaS69TULu4IKbAK9YBeFN/QFhR84/St2fkd5RO8drgN0nPjieVlePc1N9mQewxkPQFXEsDAS3ws3xiBrw7YpHMn7mAk2VZ5y4F3vuEbGL7dIgWbG5UqGXdFGpyUnD91b7Tq+oMCrYhFIquOOIi3eqXirgmdZqOpmdBjDpM97t3oLKpCJDKTU9ceQSAVRSfP4HAN2LSbHTj/n4IZsVzpfcgTuXq0jHIRfrM3VPWnkidpI0v5cWMpJwAGvWcYmR7mwKCeSTJJ5/iE+0usbc/BaUQxFberLf2+QgIsNaR9V9iVnswwMQ0KVGGitsrEuCiTPv5Nlhi/WK8qvw42sFt098EPToWJ7/yHYAEEwt5Wr+4mAh3rNiLFfFB85JWM4ScrHyufHcHU4weqb/Bg5MD2ctO5fL7sp0vSOLSS3ziBpganU8Wzj7rLbaMrJbrhyZuhm6d7XdH/TvROFbTKO5bXHxwOJ77k/AF69c3i3JH6DmmTxH1Kff4w=","iv":"8m8VfEeiyyBf6PTHdcKFVw==","data":"7B/LVElTJlN5YYnYyYaeht9WKr/vZftXx/O65HI/Uqn+02kxkYHKgLdRWJiyo0SQFapqgWKltuOW6Qce/vxOdJeG6yCqqI/UUF254o+YgAeDuI2nh7egDTJZhxN3RuTGJXOwIX54ykWK30AajAJWcTaC6cgGmc418EUauLu2mLXQf0i0s5cou/lZDtmjn0ZqQ35LNvB6BoMcfwSg4TVtW87kqELnAtbErfF22Cx9L1xfyyR7xcbMzrHXejBNGnKehnXGHd1YnH7DC+oOppjYloJ577+d/bcYZiYWmZOWhlOqcgg2rHg8rGgDKuPhyd5LVaydQ+HTOI+1Z7AetZ9szO/xfdUm46HCq6NjjTARD6CjLIUb+9aX1o9XUi7o6Fd1o6d8ATlEO1wRH6KdXK9QBhbyEgw4HL1IryChWK3ETvq9OS77yEo0sNpHLFijK/W+hV93oqaB7DniIFn21IzgxQ=="})
+// @Success 202 {object} model.Response{data=model.JobAccepted}
+// @Failure 400 {object} model.Response
+// @Failure 401 {object} model.Response
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 413 {object} model.Response
+// @Failure 429 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Failure 502 {object} model.Response
+// @Failure 503 {object} model.Response
+// @Failure 507 {object} model.Response
+// @Security BearerAuth
+// @Router /v1/migration/sync/copy [post]
+func copy(wr http.ResponseWriter, r *http.Request) {
+	startMigration(wr, r, "sync/copy", "copy")
+}
 
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
-		}
-	} else {
-		err = json.NewDecoder(r.Body).Decode(&syncConfig)
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
-		}
+// sync, copy 공통 처리. rclone 작업을 비동기로 시작하고 job id를 바로 반환한다.
+func startMigration(wr http.ResponseWriter, r *http.Request, method string, operation string) {
+	record := newAuditRecord(r, operation)
+	reject := func(status int, code string, message string, details map[string]interface{}) {
+		writeError(wr, status, code, message, details)
+		auditRejected(record, code, message)
+	}
+	// 종료 중에는 새 job을 받지 않는다.
+	if draining.Load() {
+		reject(http.StatusServiceUnavailable, errCodeShuttingDown, errShuttingDown.Error(), nil)
+		return
 	}
 
-	//var syncConfig model.SyncConfig
-	//err := json.NewDecoder(r.Body).Decode(&syncConfig)
-
-	//      sync 내용 시작
-	rcloneInitialize()
-
-	srcfs := ":" + syncConfig.Src.StorageType + ",access_key_id=" + syncConfig.Src.AccessKeyId + ",secret_access_key=" + syncConfig.Src.SecretAccessKey + ",endpoint=\"" + syncConfig.Src.Endpoint + "\":"
-	if syncConfig.Src.Bucket != "" {
-		srcfs = srcfs + syncConfig.Src.Bucket
+	var syncConfig model.SyncConfig
+	var key *payloadKey
+	err := traceStep(r.Context(), "decode payload", func() (err error) {
+		if config.Env.IsEncryption == "true" {
+			var payload model.HybridPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				return err
+			}
+			syncConfig, key, err = decodeSyncConfig(payload)
+			return err
+		}
+		return json.NewDecoder(r.Body).Decode(&syncConfig)
+	})
+	if err != nil {
+		status, code := decodeFailure(err)
+		reject(status, code, err.Error(), nil)
+		return
 	}
-	dstfs := ":" + syncConfig.Dst.StorageType + ",access_key_id=" + syncConfig.Dst.AccessKeyId + ",secret_access_key=" + syncConfig.Dst.SecretAccessKey + ",endpoint=\"" + syncConfig.Dst.Endpoint + "\":"
-	if syncConfig.Dst.Bucket != "" {
-		dstfs = dstfs + syncConfig.Dst.Bucket
+	wr = withResponseEncryption(wr, key)
+	record.Src = auditStorage(syncConfig.Src)
+	record.Dst = auditStorage(syncConfig.Dst)
+	record.DryRun = syncConfig.DryRun
+	priority, err := parseJobPriority(syncConfig.Priority)
+	if err != nil {
+		reject(http.StatusBadRequest, errCodeInvalidRequest, err.Error(), nil)
+		return
 	}
 
-	var syncRequest = model.SyncRequest{
-		SrcFs: srcfs,
-		DstFs: dstfs,
+	permission := permCopy
+	if operation == "sync" {
+		permission = permSync
+	}
+	if err := authorizeStorage(r, permission, syncConfig.Src, syncConfig.Dst); err != nil {
+		writeForbidden(wr, err)
+		auditRejected(record, errCodeForbidden, err.Error())
+		return
 	}
 
-	syncRequestJSON, err := json.Marshal(syncRequest)
+	_, fsSpan := tracer.Start(r.Context(), "build fs")
+	rcloneFilter, err := buildRcloneFilter(syncConfig.Filter)
 	if err != nil {
-		wr.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(wr, err)
+		fsSpan.End()
+		reject(http.StatusBadRequest, errCodeInvalidFilter, err.Error(), nil)
 		return
 	}
-
-	out, status := rcloneRPC("sync/sync", string(syncRequestJSON))
-	// sync 내용 시작 끝
-
-	wr.Header().Add("Content-type", "application/json")
-	var resultjson map[string]interface{}
-	json.Unmarshal([]byte(out), &resultjson)
-
-	if status == 200 {
-		wr.WriteHeader(status)
-		fmt.Fprint(wr, resultjson)
-	} else {
-		wr.WriteHeader(status)
-		fmt.Println(resultjson["error"].(string))
-		fmt.Fprint(wr, errors.New("an unknown error occurred"))
+	rcloneConfig, dstOptions, err := buildTuning(syncConfig.Tuning, syncConfig.Dst)
+	if err != nil {
+		fsSpan.End()
+		reject(http.StatusBadRequest, errCodeInvalidTuning, err.Error(), nil)
+		return
 	}
-}
 
-// @Summary Copying Between Storage
-// @Description Copying Between Storage.
-// @Description Example request body before encoding :
-// @Description {
-// @Description     "src": {
-// @Description         "storageType": "s3",
-// @Description         "endpoint": "http://url.co.kr",
-// @Description         "accessKeyId": "admin",
-// @Description         "secretAccessKey": "admin",
-// @Description         "bucket": "abc"
-// @Description     },
-// @Description     "dst": {
-// @Description         "storageType": "s3",
-// @Description         "endpoint": "http://url.com",
-// @Description         "accessKeyId": "admin",
-// @Description         "secretAccessKey": "admin",
-// @Description         "bucket": "abcd"
-// @Description     }
-// @Description }
-// @Tags Migration
-// @Accept json
-// @Produce json
-// @Param payload body model.HybridPayload true "encode base64 model.SyncConfig" SchemaExample({"key":"iCJcdWA198EF1uuzePq8M+Onsx8/4KJ9Mm9YDY+j+KUSxV+/4YyRkeDiuVAyRHkU8UUTyXS/zRhb2t8gUMKJiJaReLFgVyrweVMxRLk2Jx1yshNQ++Y/Cq4+Jvvk9lqsqpbmcoP2VrQoP4C4vymWQ+jwaV/vkJfUK0j6l6LlZenL8PmSzbhzO8kFVaS69TULu4IKbAK9YBeFN/QFhR84/St2fkd5RO8drgN0nPjieVlePc1N9mQewxkPQFXEsDAS3ws3xiBrw7YpHMn7mAk2VZ5y4F3vuEbGL7dIgWbG5UqGXdFGpyUnD91b7Tq+oMCrYhFIquOOIi3eqXirgmdZqOpmdBjDpM97t3oLKpCJDKTU9ceQSAVRSfP4HAN2LSbHTj/n4IZsVzpfcgTuXq0jHIRfrM3VPWnkidpI0v5cWMpJwAGvWcYmR7mwKCeSTJJ5/iE+0usbc/BaUQxFberLf2+QgIsNaR9V9iVnswwMQ0KVGGitsrEuCiTPv5Nlhi/WK8qvw42sFt098EPToWJ7/yHYAEEwt5Wr+4mAh3rNiLFfFB85JWM4ScrHyufHcHU4weqb/Bg5MD2ctO5fL7sp0vSOLSS3ziBpganU8Wzj7rLbaMrJbrhyZuhm6d7XdH/TvROFbTKO5bXHxwOJ77k/AF69c3i3JH6DmmTxH1Kff4w=","iv":"8m8VfEeiyyBf6PTHdcKFVw==","data":"7B/LVElTJlN5YYnYyYaeht9WKr/vZftXx/O65HI/Uqn+02kxkYHKgLdRWJiyo0SQFapqgWKltuOW6Qce/vxOdJeG6yCqqI/UUF254o+YgAeDuI2nh7egDTJZhxN3RuTGJXOwIX54ykWK30AajAJWcTaC6cgGmc418EUauLu2mLXQf0i0s5cou/lZDtmjn0ZqQ35LNvB6BoMcfwSg4TVtW87kqELnAtbErfF22Cx9L1xfyyR7xcbMzrHXejBNGnKehnXGHd1YnH7DC+oOppjYloJ577+d/bcYZiYWmZOWhlOqcgg2rHg8rGgDKuPhyd5LVaydQ+HTOI+1Z7AetZ9szO/xfdUm46HCq6NjjTARD6CjLIUb+9aX1o9XUi7o6Fd1o6d8ATlEO1wRH6KdXK9QBhbyEgw4HL1IryChWK3ETvq9OS77yEo0sNpHLFijK/W+hV93oqaB7DniIFn21IzgxQ=="})
-// @Success 200 {object} string
-// @Failure 500 {object} string
-// @Router /v1/migration/sync/copy [post]
-func copy(wr http.ResponseWriter, r *http.Request) {
-	var syncConfig model.SyncConfig
-	var err error
-	if config.Env.IsEncryption == "true" {
-		var payload model.HybridPayload
-		_ = json.NewDecoder(r.Body).Decode(&payload)
-		syncConfig, err = decodeSyncConfig(payload)
-
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
-		}
-	} else {
-		err = json.NewDecoder(r.Body).Decode(&syncConfig)
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
-		}
+	srcfs, err := storageFs(syncConfig.Src)
+	if err != nil {
+		fsSpan.End()
+		reject(http.StatusBadRequest, errCodeInvalidStorage, err.Error(), map[string]interface{}{"storage": "src"})
+		return
+	}
+	dstfs, err := storageFs(syncConfig.Dst, dstOptions...)
+	fsSpan.End()
+	if err != nil {
+		reject(http.StatusBadRequest, errCodeInvalidStorage, err.Error(), map[string]interface{}{"storage": "dst"})
+		return
 	}
 
 	rcloneInitialize()
-	srcfs := ":" + syncConfig.Src.StorageType + ",access_key_id=" + syncConfig.Src.AccessKeyId + ",secret_access_key=" + syncConfig.Src.SecretAccessKey + ",endpoint=\"" + syncConfig.Src.Endpoint + "\":"
-	if syncConfig.Src.Bucket != "" {
-		srcfs = srcfs + syncConfig.Src.Bucket
-	}
-	dstfs := ":" + syncConfig.Dst.StorageType + ",access_key_id=" + syncConfig.Dst.AccessKeyId + ",secret_access_key=" + syncConfig.Dst.SecretAccessKey + ",endpoint=\"" + syncConfig.Dst.Endpoint + "\":"
-	if syncConfig.Dst.Bucket != "" {
-		dstfs = dstfs + syncConfig.Dst.Bucket
-	}
 	var syncRequest = model.SyncRequest{
-		SrcFs: srcfs,
-		DstFs: dstfs,
+		SrcFs:  srcfs,
+		DstFs:  dstfs,
+		Filter: rcloneFilter,
+	}
+	rcloneConfig.DryRun = syncConfig.DryRun
+	if *rcloneConfig != (model.RcloneConfig{}) {
+		syncRequest.Config = rcloneConfig
 	}
 
-	syncRequestJSON, err := json.Marshal(syncRequest)
+	j := jobs.create(operation, method, syncRequest)
+	if c, ok := callerFromContext(r.Context()); ok {
+		j.setOwner(c.Subject)
+	}
+	j.setPriority(priority)
+	j.setAuditRecord(record)
+
+	// 실행할 자리가 없으면 대기열에 넣고, 대기열도 가득 차면 거절한다.
+	start, err := scheduler.admit(j, "")
+	// 등록하는 사이 서버 종료로 interrupted가 된 job은 checkpoint에 저장되어 재시작 후 재개할 수 있다.
+	if errors.Is(err, errJobNotActive) {
+		writeData(wr, http.StatusAccepted, model.JobAccepted{JobId: j.id, State: j.state()})
+		return
+	}
 	if err != nil {
-		wr.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(wr, err)
+		jobs.remove(j.id)
+		code := writeJobLimit(wr, err)
+		auditRejected(record, code, err.Error())
 		return
 	}
-
-	out, status := rcloneRPC("sync/copy", string(syncRequestJSON))
-
-	wr.Header().Add("Content-type", "application/json")
-
-	var resultjson map[string]interface{}
-	json.Unmarshal([]byte(out), &resultjson)
-
-	if status == 200 {
-		wr.WriteHeader(status)
-		fmt.Fprint(wr, resultjson)
-	} else {
-		wr.WriteHeader(status)
-		fmt.Println(resultjson["error"].(string))
-		fmt.Fprint(wr, errors.New("an unknown error occurred"))
+	j.startTrace(r.Context())
+	if start {
+		if status, err := j.run(); err != nil {
+			// 시작하지 못한 job의 span도 오류로 끝낸다.
+			j.fail(err)
+			j.endTrace(j.snapshot(), model.JobProgress{})
+			jobs.remove(j.id)
+			scheduler.dispatch()
+			status, code := classifyRcloneError(err.Error(), status)
+			record.JobId = j.id
+			reject(status, code, j.redact(err.Error()), map[string]interface{}{"method": method})
+			return
+		}
 	}
+
+	status := j.report()
+	writeData(wr, http.StatusAccepted, model.JobAccepted{JobId: j.id, State: status.State, QueuePosition: status.QueuePosition})
 }
 
 // @Summary Check bucket list
 // @Description Check bucket list.
+// @Description For sftp and local storage the directories under "bucket" are listed.
+// @Description "storageType" is one of s3, azureblob, google cloud storage (gcs), swift, sftp and local.
+// @Description "local" is rejected unless the server enables it, and its "bucket" path must be under the configured local root.
+// @Description Non-S3 storages take their settings from "azureblob", "gcs", "swift" or "sftp" (see model.StorageConfig).
+// @Description "sftp" also requires "knownHosts", the OpenSSH known_hosts lines of the server, unless the server disables host key checking.
+// @Description S3 storages also accept "provider" (AWS, Ceph, Minio, Other, ...), "region", "forcePathStyle", "v2Auth", "locationConstraint" and "acl".
+// @Description Storage failures are reported in error.code as AUTH_FAILED, PERMISSION_DENIED, BUCKET_NOT_FOUND, QUOTA_EXCEEDED, RATE_LIMITED, TLS_ERROR, ENDPOINT_UNREACHABLE or RCLONE_ERROR.
+// @Description Calls denied by the RBAC policy return 403 with error.code FORBIDDEN. Requires the "bucket:list" permission for the storage endpoints.
+// @Description With response encryption enabled the body is model.EncryptedPayload, encrypted with the AES key of the request.
+// @Description Malformed or undecryptable bodies return 400 with error.code INVALID_REQUEST. An expired encrypted request returns 401 with REQUEST_EXPIRED and a reused nonce returns 409 with REPLAYED_REQUEST.
 // @Description Example request body before encoding :
 // @Description 	{
 // @Description 		"storageType": "s3",
@@ -225,33 +275,67 @@ func copy(wr http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param payload body model.HybridPayload true "encode base64 model.StorageConfig" SchemaExample({"key":"iCJcdWA198EF1uuzePq8M+Onsx8/4KJ9Mm9YDY+j+KUSxV+/4YyRkeDiuVAyRHkU8UUTyXS/zRhb2t8gUMKJiJaReLFgVyrweVMxRLk2Jx1yshNQ++Y/Cq4+Jvvk9lqsqpbmcoP2VrQoP4C4vymWQ+jwaV/vkJfUK0j6l6LlZenL8PmSzbhzO8kFVaS69TULu4IKbAK9YBeFN/QFhR84/St2fkd5RO8drgN0nPjieVlePc1N9mQewxkPQFXEsDAS3ws3xiBrw7YpHMn7mAk2VZ5y4F3vuEbGL7dIgWbG5UqGXdFGpyUnD91b7Tq+oMCrYhFIquOOIi3eqXirgmdZqOpmdBjDpM97t3oLKpCJDKTU9ceQSAVRSfP4HAN2LSbHTj/n4IZsVzpfcgTuXq0jHIRfrM3VPWnkidpI0v5cWMpJwAGvWcYmR7mwKCeSTJJ5/iE+0usbc/BaUQxFberLf2+QgIsNaR9V9iVnswwMQ0KVGGitsrEuCiTPv5Nlhi/WK8qvw42sFt098EPToWJ7/yHYAEEwt5Wr+4mAh3rNiLFfFB85JWM4ScrHyufHcHU4weqb/Bg5MD2ctO5fL7sp0vSOLSS3ziBpganU8Wzj7rLbaMrJbrhyZuhm6d7XdH/TvROFbTKO5bXHxwOJ77k/AF69c3i3JH6DmmTxH1Kff4w=","iv":"8m8VfEeiyyBf6PTHdcKFVw==","data":"7B/LVElTJlN5YYnYyYaeht9WKr/vZftXx/O65HI/Uqn+02kxkYHKgLdRWJiyo0SQFapqgWKltuOW6Qce/vxOdJeG6yCqqI/UUF254o+YgAeDuI2nh7egDTJZhxN3RuTGJXOwIX54ykWK30AajAJWcTaC6cgGmc418EUauLu2mLXQf0i0s5cou/lZDtmjn0ZqQ35LNvB6BoMcfwSg4TVtW87kqELnAtbErfF22Cx9L1xfyyR7xcbMzrHXejBNGnKehnXGHd1YnH7DC+oOppjYloJ577+d/bcYZiYWmZOWhlOqcgg2rHg8rGgDKuPhyd5LVaydQ+HTOI+1Z7AetZ9szO/xfdUm46HCq6NjjTARD6CjLIUb+9aX1o9XUi7o6Fd1o6d8ATlEO1wRH6KdXK9QBhbyEgw4HL1IryChWK3ETvq9OS77yEo0sNpHLFijK/W+hV93oqaB7DniIFn21IzgxQ=="})
-// @Success 200 {array} object
-// @Failure 404 {object} string
-// @Failure 500 {object} string
+// @Success 200 {object} model.Response{data=object}
+// @Failure 400 {object} model.Response
+// @Failure 401 {object} model.Response
+// @Failure 403 {object} model.Response
+// @Failure 404 {object} model.Response
+// @Failure 409 {object} model.Response
+// @Failure 413 {object} model.Response
+// @Failure 429 {object} model.Response
+// @Failure 500 {object} model.Response
+// @Failure 502 {object} model.Response
+// @Failure 507 {object} model.Response
+// @Security BearerAuth
 // @Router /v1/migration/operations/list [post]
 func bucketList(wr http.ResponseWriter, r *http.Request) {
+	record := newAuditRecord(r, "list")
+	reject := func(status int, code string, message string, details map[string]interface{}) {
+		writeError(wr, status, code, message, details)
+		auditRejected(record, code, message)
+	}
+	fail := func(status int, code string, message string, details map[string]interface{}) {
+		writeError(wr, status, code, message, details)
+		auditOutcome(record, model.AuditStatusError, code, message)
+	}
+
 	var storageConfig model.StorageConfig
-	var err error
-	if config.Env.IsEncryption == "true" {
-		var payload model.HybridPayload
-		_ = json.NewDecoder(r.Body).Decode(&payload)
-		storageConfig, err = decodeStorageConfig(payload)
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
-		}
-	} else {
-		err = json.NewDecoder(r.Body).Decode(&storageConfig)
-		if err != nil {
-			wr.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(wr, err)
-			return
+	var key *payloadKey
+	err := traceStep(r.Context(), "decode payload", func() (err error) {
+		if config.Env.IsEncryption == "true" {
+			var payload model.HybridPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				return err
+			}
+			storageConfig, key, err = decodeStorageConfig(payload)
+			return err
 		}
+		return json.NewDecoder(r.Body).Decode(&storageConfig)
+	})
+	if err != nil {
+		status, code := decodeFailure(err)
+		reject(status, code, err.Error(), nil)
+		return
+	}
+	wr = withResponseEncryption(wr, key)
+
+	record.Src = auditStorage(storageConfig)
+
+	if err := authorizeStorage(r, permBucketList, storageConfig); err != nil {
+		writeForbidden(wr, err)
+		auditRejected(record, errCodeForbidden, err.Error())
+		return
+	}
+
+	_, fsSpan := tracer.Start(r.Context(), "build fs")
+	fs, err := listFs(storageConfig)
+	fsSpan.End()
+	if err != nil {
+		reject(http.StatusBadRequest, errCodeInvalidStorage, err.Error(), nil)
+		return
 	}
 
 	rcloneInitialize()
-	fs := ":" + storageConfig.StorageType + ",access_key_id=" + storageConfig.AccessKeyId + ",secret_access_key=" + storageConfig.SecretAccessKey + ",endpoint=\"" + storageConfig.Endpoint + "\":"
 
 	var listRequest = model.ListRequest{
 		Fs:     fs,
@@ -261,24 +345,24 @@ func bucketList(wr http.ResponseWriter, r *http.Request) {
 
 	requestJSON, err := json.Marshal(listRequest)
 	if err != nil {
-		wr.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(wr, err)
+		fail(http.StatusInternalServerError, errCodeInternal, err.Error(), nil)
 		return
 	}
 
-	out, status := rcloneRPC("operations/list", string(requestJSON))
+	out, status := callRclone(r.Context(), "operations/list", string(requestJSON))
 
-	wr.Header().Add("Content-type", "application/json")
+	if status != http.StatusOK {
+		message := rcloneErrorMessage(out, status)
+		status, code := classifyRcloneError(message, status)
+		fail(status, code, redactSecrets(message, fs), map[string]interface{}{"method": "operations/list"})
+		return
+	}
 
 	var resultjson map[string]interface{}
-	json.Unmarshal([]byte(out), &resultjson)
-
-	if status == 200 {
-		wr.WriteHeader(status)
-		fmt.Fprint(wr, resultjson)
-	} else {
-		wr.WriteHeader(status)
-		fmt.Println(resultjson["error"].(string))
-		fmt.Fprint(wr, errors.New("an unknown error occurred"))
+	if err := json.Unmarshal([]byte(out), &resultjson); err != nil {
+		fail(http.StatusInternalServerError, errCodeInternal, err.Error(), nil)
+		return
 	}
+	writeData(wr, status, resultjson)
+	auditOutcome(record, model.AuditStatusSuccess, "", "")
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"kps-migration-api/config"
 	"kps-migration-api/model"
@@ -17,10 +18,14 @@ func withRcloneMock(t *testing.T, fn func(initCalled *bool, lastMethod *string,
 
 	oldInit := rcloneInitialize
 	oldRPC := rcloneRPC
+	oldInterval := jobPollInterval
 	defer func() {
+		stopJobRoutines(t)
 		rcloneInitialize = oldInit
 		rcloneRPC = oldRPC
+		jobPollInterval = oldInterval
 	}()
+	jobPollInterval = 5 * time.Millisecond
 
 	initCalled := false
 	lastMethod := ""
@@ -68,14 +73,16 @@ func TestSync_PlainJSON_Success(t *testing.T) {
 		}
 		body, _ := json.Marshal(syncCfg)
 
+		setOut(`{"jobid":1}`)
+
 		req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body))
 		w := httptest.NewRecorder()
 
 		sync(w, req)
 
 		res := w.Result()
-		if res.StatusCode != http.StatusOK {
-			t.Fatalf("expected status 200, got %d", res.StatusCode)
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", res.StatusCode)
 		}
 
 		if !*initCalled {
@@ -87,10 +94,24 @@ func TestSync_PlainJSON_Success(t *testing.T) {
 		if *lastPayload == "" {
 			t.Fatalf("expected RPC payload to be non-empty")
 		}
+
+		var syncRequest model.SyncRequest
+		if err := json.Unmarshal([]byte(*lastPayload), &syncRequest); err != nil {
+			t.Fatalf("unmarshal RPC payload: %v", err)
+		}
+		if !syncRequest.Async || syncRequest.Group == "" {
+			t.Fatalf("expected async RPC with a job group, got %+v", syncRequest)
+		}
+
+		var accepted model.JobAccepted
+		decodeResponse(t, res.Body, &accepted)
+		if _, ok := jobs.get(accepted.JobId); !ok {
+			t.Fatalf("expected job %q to be registered", accepted.JobId)
+		}
 	})
 }
 
-func TestSync_InvalidJSON_Returns500(t *testing.T) {
+func TestSync_InvalidJSON_Returns400(t *testing.T) {
 	if config.Env != nil {
 		config.Env.IsEncryption = "false"
 	}
@@ -102,8 +123,8 @@ func TestSync_InvalidJSON_Returns500(t *testing.T) {
 	sync(w, req)
 
 	res := w.Result()
-	if res.StatusCode != http.StatusInternalServerError {
-		t.Fatalf("expected status 500, got %d", res.StatusCode)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", res.StatusCode)
 	}
 }
 
@@ -129,14 +150,16 @@ func TestCopy_PlainJSON_UsesSyncCopyRPC(t *testing.T) {
 		}
 		body, _ := json.Marshal(syncCfg)
 
+		setOut(`{"jobid":1}`)
+
 		req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/copy", bytes.NewReader(body))
 		w := httptest.NewRecorder()
 
 		copy(w, req)
 
 		res := w.Result()
-		if res.StatusCode != http.StatusOK {
-			t.Fatalf("expected status 200, got %d", res.StatusCode)
+		if res.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", res.StatusCode)
 		}
 		if !*initCalled {
 			t.Fatalf("expected rcloneInitialize to be called")
@@ -216,7 +239,7 @@ func TestBucketList_RcloneError_Returns500(t *testing.T) {
 	})
 }
 
-func TestSync_Encrypted_DecodeError_Returns500(t *testing.T) {
+func TestSync_Encrypted_DecodeError_Returns400(t *testing.T) {
 	if config.Env != nil {
 		config.Env.IsEncryption = "true"
 	}
@@ -224,8 +247,8 @@ func TestSync_Encrypted_DecodeError_Returns500(t *testing.T) {
 	oldDecode := decodeSyncConfig
 	defer func() { decodeSyncConfig = oldDecode }()
 
-	decodeSyncConfig = func(payload model.HybridPayload) (model.SyncConfig, error) {
-		return model.SyncConfig{}, errors.New("decode failed")
+	decodeSyncConfig = func(payload model.HybridPayload) (model.SyncConfig, *payloadKey, error) {
+		return model.SyncConfig{}, nil, errors.New("decode failed")
 	}
 
 	body := []byte(`{"encryptedData":"x"}`)
@@ -235,12 +258,12 @@ func TestSync_Encrypted_DecodeError_Returns500(t *testing.T) {
 	sync(w, req)
 
 	res := w.Result()
-	if res.StatusCode != http.StatusInternalServerError {
-		t.Fatalf("expected status 500, got %d", res.StatusCode)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", res.StatusCode)
 	}
 }
 
-func TestCopy_InvalidJSON_Returns500(t *testing.T) {
+func TestCopy_InvalidJSON_Returns400(t *testing.T) {
 	if config.Env != nil {
 		config.Env.IsEncryption = "false"
 	}
@@ -252,8 +275,8 @@ func TestCopy_InvalidJSON_Returns500(t *testing.T) {
 	copy(w, req)
 
 	res := w.Result()
-	if res.StatusCode != http.StatusInternalServerError {
-		t.Fatalf("expected status 500, got %d", res.StatusCode)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", res.StatusCode)
 	}
 }
 
@@ -265,13 +288,13 @@ func TestBucketList_Encrypted_UsesDecodeStorageConfigAndRPC(t *testing.T) {
 	oldDecode := decodeStorageConfig
 	defer func() { decodeStorageConfig = oldDecode }()
 
-	decodeStorageConfig = func(payload model.HybridPayload) (model.StorageConfig, error) {
+	decodeStorageConfig = func(payload model.HybridPayload) (model.StorageConfig, *payloadKey, error) {
 		return model.StorageConfig{
 			StorageType:     "s3",
 			Endpoint:        "http://endpoint",
 			AccessKeyId:     "key",
 			SecretAccessKey: "secret",
-		}, nil
+		}, nil, nil
 	}
 
 	withRcloneMock(t, func(initCalled *bool, lastMethod *string, lastPayload *string, setStatus func(int), setOut func(string)) {
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+	"slices"
+	"strconv"
+	gosync "sync"
+)
+
+var (
+	errQueueFull    = errors.New("job queue is full")
+	errUserJobLimit = errors.New("too many active jobs for the user")
+)
+
+var jobPriorities = map[string]int{
+	model.JobPriorityLow:    0,
+	model.JobPriorityNormal: 1,
+	model.JobPriorityHigh:   2,
+}
+
+// 우선순위가 없는 job(이전 버전의 checkpoint)은 normal로 본다.
+func jobPriorityRank(priority string) int {
+	if rank, ok := jobPriorities[priority]; ok {
+		return rank
+	}
+	return jobPriorities[model.JobPriorityNormal]
+}
+
+// 요청의 우선순위를 확인한다. 비어 있으면 normal이다.
+func parseJobPriority(priority string) (string, error) {
+	if priority == "" {
+		return model.JobPriorityNormal, nil
+	}
+	if _, ok := jobPriorities[priority]; !ok {
+		return "", fmt.Errorf("priority must be one of low, normal, high, got %q", priority)
+	}
+	return priority, nil
+}
+
+// 동시에 실행하는 job 수를 MaxConcurrentJobs로 제한한다.
+// 자리가 없으면 우선순위, 요청 순서대로 대기열에 넣고 실행 중인 job이 끝나면 꺼내 실행한다.
+type jobScheduler struct {
+	mu    gosync.Mutex
+	queue []*job
+}
+
+var scheduler = &jobScheduler{}
+
+// job을 바로 실행할지 대기열에 넣을지 정하고 상태를 바꾼다. 바로 실행해야 하면 true를 반환한다.
+// action이 없으면 새로 등록한(pending) job만, action이 있으면(resume) 일시정지했거나 중지된 job만 받고 그 동작을 기록한다.
+// 실행 중인 job 수는 admit이나 dispatch가 running으로 바꾼 job만 센다.
+func (s *jobScheduler) admit(j *job, action string) (bool, error) {
+	if !admittable(j.state(), action) {
+		return false, errJobNotActive
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+
+	owner := j.owner()
+	if limit := config.Env.MaxJobsPerUser; limit > 0 && owner != "" {
+		count := countJobs(j, func(other *job, state model.JobState) bool {
+			return other.owner() == owner && (state == model.JobStateRunning || state == model.JobStateQueued)
+		})
+		if count >= limit {
+			return false, errUserJobLimit
+		}
+	}
+	start := len(s.queue) == 0 && countJobs(j, isRunning) < config.Env.MaxConcurrentJobs
+	if !start && len(s.queue) >= config.Env.MaxQueuedJobs {
+		return false, errQueueFull
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !admittable(j.status.State, action) {
+		return false, errJobNotActive
+	}
+	if action != "" {
+		j.recordLocked(action)
+	}
+	if start {
+		j.status.State = model.JobStateRunning
+		return true, nil
+	}
+	j.status.State = model.JobStateQueued
+	s.insertLocked(j)
+	return false, nil
+}
+
+// 같은 우선순위의 job 뒤에 넣는다. 우선순위는 job을 만든 뒤 바뀌지 않는다.
+func (s *jobScheduler) insertLocked(j *job) {
+	priority := jobPriorityRank(j.status.Priority)
+	i := len(s.queue)
+	for i > 0 && jobPriorityRank(s.queue[i-1].status.Priority) < priority {
+		i--
+	}
+	s.queue = slices.Insert(s.queue, i, j)
+}
+
+// 자리가 나면 대기 중인 job을 실행한다. 종료 중에는 새로 실행하지 않는다.
+func (s *jobScheduler) dispatch() {
+	if draining.Load() {
+		return
+	}
+	s.mu.Lock()
+	s.pruneLocked()
+	var started []*job
+	for len(s.queue) > 0 && countJobs(nil, isRunning) < config.Env.MaxConcurrentJobs {
+		j := s.queue[0]
+		s.queue = s.queue[1:]
+		j.mu.Lock()
+		j.status.State = model.JobStateRunning
+		j.mu.Unlock()
+		started = append(started, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range started {
+		goJob(j.start)
+	}
+}
+
+// 대기 중인 job의 순서 (1부터). 대기열에 없으면 0
+func (s *jobScheduler) position(j *job) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	for i, queued := range s.queue {
+		if queued == j {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// 취소, 서버 종료로 더 이상 대기하지 않는 job을 뺀다.
+func (s *jobScheduler) pruneLocked() {
+	queue := s.queue[:0]
+	for _, j := range s.queue {
+		if j.state() == model.JobStateQueued {
+			queue = append(queue, j)
+		}
+	}
+	for i := len(queue); i < len(s.queue); i++ {
+		s.queue[i] = nil
+	}
+	s.queue = queue
+}
+
+func admittable(state model.JobState, action string) bool {
+	if action == "" {
+		return state == model.JobStatePending
+	}
+	return resumable(state)
+}
+
+func resumable(state model.JobState) bool {
+	return state == model.JobStatePaused || state == model.JobStateInterrupted
+}
+
+func isRunning(_ *job, state model.JobState) bool {
+	return state == model.JobStateRunning
+}
+
+// except를 제외하고 조건에 맞는 job 수
+func countJobs(except *job, match func(*job, model.JobState) bool) int {
+	count := 0
+	for _, j := range jobs.list() {
+		if j != except && match(j, j.state()) {
+			count++
+		}
+	}
+	return count
+}
+
+// 대기열이 가득 찼거나 사용자별 제한을 넘은 요청에 429와 Retry-After를 응답하고 error.code를 반환한다.
+func writeJobLimit(wr http.ResponseWriter, err error) string {
+	details := map[string]interface{}{"maxQueuedJobs": config.Env.MaxQueuedJobs}
+	code := errCodeQueueFull
+	if errors.Is(err, errUserJobLimit) {
+		details = map[string]interface{}{"maxJobsPerUser": config.Env.MaxJobsPerUser}
+		code = errCodeUserJobLimit
+	}
+	wr.Header().Set("Retry-After", strconv.Itoa(int(config.Env.QueueRetryAfter.Seconds())))
+	writeError(wr, http.StatusTooManyRequests, code, err.Error(), details)
+	return code
+}
+
+func isJobLimit(err error) bool {
+	return errors.Is(err, errQueueFull) || errors.Is(err, errUserJobLimit)
+}
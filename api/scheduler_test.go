@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+
+	"github.com/gorilla/mux"
+)
+
+// 시작한 rclone 작업마다 job/stop을 호출할 때까지 실행 중으로 응답한다.
+func withRcloneJobs(t *testing.T) {
+	t.Helper()
+	var mu gosync.Mutex
+	nextId := int64(0)
+	stopped := map[int64]bool{}
+
+	oldInit, oldRPC, oldInterval := rcloneInitialize, rcloneRPC, jobPollInterval
+	t.Cleanup(func() {
+		stopJobRoutines(t)
+		rcloneInitialize, rcloneRPC, jobPollInterval = oldInit, oldRPC, oldInterval
+	})
+	rcloneInitialize = func() {}
+	rcloneRPC = func(method, in string) (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		var request model.RcloneJobRequest
+		json.Unmarshal([]byte(in), &request)
+		switch method {
+		case "sync/sync", "sync/copy":
+			nextId++
+			return fmt.Sprintf(`{"jobid":%d}`, nextId), http.StatusOK
+		case "job/stop":
+			stopped[request.JobId] = true
+			return `{}`, http.StatusOK
+		case "job/status":
+			if stopped[request.JobId] {
+				return fmt.Sprintf(`{"id":%d,"finished":true,"success":false,"error":"context canceled"}`, request.JobId), http.StatusOK
+			}
+			return fmt.Sprintf(`{"id":%d,"finished":false}`, request.JobId), http.StatusOK
+		}
+		return `{}`, http.StatusOK
+	}
+	jobPollInterval = 5 * time.Millisecond
+}
+
+// 테스트 동안 빈 job 저장소, 대기열과 scheduler 설정을 사용한다.
+func withScheduler(t *testing.T, maxConcurrent int, maxQueued int, maxPerUser int) {
+	t.Helper()
+	withShutdownState(t, 0)
+	allowLocalStorage(t, "/")
+	oldScheduler := scheduler
+	scheduler = &jobScheduler{}
+	t.Cleanup(func() {
+		stopJobRoutines(t)
+		scheduler = oldScheduler
+	})
+	config.Env.IsEncryption = "false"
+	config.Env.MaxConcurrentJobs = maxConcurrent
+	config.Env.MaxQueuedJobs = maxQueued
+	config.Env.MaxJobsPerUser = maxPerUser
+	config.Env.QueueRetryAfter = 45 * time.Second
+}
+
+func submitSync(t *testing.T, user string, priority string) (*httptest.ResponseRecorder, model.JobAccepted) {
+	t.Helper()
+	body, _ := json.Marshal(model.SyncConfig{
+		Src:      model.StorageConfig{StorageType: "local", Bucket: "/src"},
+		Dst:      model.StorageConfig{StorageType: "local", Bucket: "/dst"},
+		Priority: priority,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), callerContextKey{}, &caller{Subject: user}))
+	w := httptest.NewRecorder()
+	sync(w, req)
+
+	var accepted model.JobAccepted
+	if w.Code == http.StatusAccepted {
+		decodeResponse(t, bytes.NewReader(w.Body.Bytes()), &accepted)
+	}
+	return w, accepted
+}
+
+func waitForState(t *testing.T, j *job, state model.JobState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for j.state() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job %s to be %s, got %s", j.id, state, j.state())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScheduler_QueueAndPriorities(t *testing.T) {
+	withAuditSink(t)
+	withRcloneJobs(t)
+	withScheduler(t, 1, 2, 0)
+
+	_, first := submitSync(t, "user-1", "")
+	_, low := submitSync(t, "user-2", model.JobPriorityLow)
+	_, high := submitSync(t, "user-3", model.JobPriorityHigh)
+	if first.State != model.JobStateRunning {
+		t.Fatalf("expected first job to run, got %+v", first)
+	}
+	if low.State != model.JobStateQueued || low.QueuePosition != 1 || high.State != model.JobStateQueued || high.QueuePosition != 1 {
+		t.Fatalf("expected high priority job ahead of low, got low=%+v high=%+v", low, high)
+	}
+
+	w := httptest.NewRecorder()
+	jobStatus(w, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/migration/jobs/"+low.JobId, nil), map[string]string{"id": low.JobId}))
+	var status model.JobStatus
+	decodeResponse(t, w.Body, &status)
+	if status.State != model.JobStateQueued || status.QueuePosition != 2 || status.Priority != model.JobPriorityLow {
+		t.Fatalf("expected low job at position 2, got %+v", status)
+	}
+
+	// 대기열이 가득 차면 429와 Retry-After로 거절한다.
+	w, _ = submitSync(t, "user-4", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "45" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if resp := decodeResponse(t, w.Body, nil); resp.Error == nil || resp.Error.Code != errCodeQueueFull {
+		t.Fatalf("expected %s, got %+v", errCodeQueueFull, resp.Error)
+	}
+	if len(jobs.list()) != 3 {
+		t.Fatalf("expected rejected job to be removed, got %d jobs", len(jobs.list()))
+	}
+
+	// 실행 중인 job이 끝나면 우선순위가 높은 job부터 실행한다.
+	j, _ := jobs.get(first.JobId)
+	if err := j.cancel(); err != nil {
+		t.Fatalf("cancel error: %v", err)
+	}
+	highJob, _ := jobs.get(high.JobId)
+	lowJob, _ := jobs.get(low.JobId)
+	waitForState(t, highJob, model.JobStateRunning)
+	if status := lowJob.report(); status.State != model.JobStateQueued || status.QueuePosition != 1 {
+		t.Fatalf("expected low job to move to position 1, got %+v", status)
+	}
+
+	// 일시정지도 자리를 비운다.
+	if err := highJob.pause(); err != nil {
+		t.Fatalf("pause error: %v", err)
+	}
+	waitForState(t, lowJob, model.JobStateRunning)
+
+	// 자리가 없으면 재개한 job은 대기열에 들어간다.
+	if err := highJob.resume(); err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	if status := highJob.report(); status.State != model.JobStateQueued || status.QueuePosition != 1 {
+		t.Fatalf("expected resumed job to be queued, got %+v", status)
+	}
+
+	// 종료 시 대기 중인 job도 interrupted로 저장한다.
+	draining.Store(true)
+	interruptJobs()
+	for _, j := range []*job{highJob, lowJob} {
+		if _, ok := j.checkpoint(); !ok {
+			t.Fatalf("expected checkpoint for %s job", j.state())
+		}
+	}
+}
+
+func TestScheduler_UserLimit(t *testing.T) {
+	withAuditSink(t)
+	withRcloneJobs(t)
+	withScheduler(t, 1, 10, 2)
+
+	submitSync(t, "user-1", "")
+	_, queued := submitSync(t, "user-1", "")
+	if queued.State != model.JobStateQueued {
+		t.Fatalf("expected second job to be queued, got %+v", queued)
+	}
+	w, _ := submitSync(t, "user-1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", w.Code)
+	}
+	if resp := decodeResponse(t, w.Body, nil); resp.Error == nil || resp.Error.Code != errCodeUserJobLimit {
+		t.Fatalf("expected %s, got %+v", errCodeUserJobLimit, resp.Error)
+	}
+	if w, _ := submitSync(t, "user-2", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("expected other user to be accepted, got %d", w.Code)
+	}
+
+	// 대기 중인 job을 취소하면 다시 요청할 수 있다.
+	j, _ := jobs.get(queued.JobId)
+	if err := j.cancel(); err != nil {
+		t.Fatalf("cancel error: %v", err)
+	}
+	if w, _ := submitSync(t, "user-1", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("expected job to be accepted after cancel, got %d", w.Code)
+	}
+
+	w, _ = submitSync(t, "user-1", "urgent")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid priority, got %d", w.Code)
+	}
+}
+
+func TestScheduler_PendingJobsAreNotCounted(t *testing.T) {
+	withAuditSink(t)
+	withRcloneJobs(t)
+	withScheduler(t, 1, 10, 1)
+
+	create := func(owner string) *job {
+		j := jobs.create("sync", "sync/sync", model.SyncRequest{})
+		j.setOwner(owner)
+		return j
+	}
+	running, queued := create("user-1"), create("user-2")
+	if start, err := scheduler.admit(running, ""); err != nil || !start {
+		t.Fatalf("expected first job to start, got %v %v", start, err)
+	}
+	if start, err := scheduler.admit(queued, ""); err != nil || start {
+		t.Fatalf("expected second job to be queued, got %v %v", start, err)
+	}
+
+	// 아직 admit하지 않은 job은 실행 중인 job으로 세지 않으므로 대기 중인 job이 실행된다.
+	pending := create("user-3")
+	if err := running.cancel(); err != nil {
+		t.Fatalf("cancel error: %v", err)
+	}
+	waitForState(t, queued, model.JobStateRunning)
+	if start, err := scheduler.admit(pending, ""); err != nil || start {
+		t.Fatalf("expected pending job to be queued, got %v %v", start, err)
+	}
+	if _, err := scheduler.admit(pending, ""); err != errJobNotActive {
+		t.Fatalf("expected a job to be admitted once, got %v", err)
+	}
+
+	// 사용자별 제한에도 admit하지 않은 job은 세지 않는다.
+	create("user-4")
+	if _, err := scheduler.admit(create("user-4"), ""); err != nil {
+		t.Fatalf("expected pending job not to count toward the user limit, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// 남은 요청(이벤트 스트림 포함)을 기다리는 시간. 지나면 연결을 끊는다.
+const serverShutdownTimeout = 5 * time.Second
+
+var errShuttingDown = errors.New("server is shutting down")
+
+var (
+	// 종료 처리가 끝나면 false
+	alive atomic.Bool
+	// 종료 신호를 받은 뒤 true. readiness를 내리고 새 job을 받지 않는다.
+	draining atomic.Bool
+)
+
+func init() {
+	alive.Store(true)
+}
+
+func writeShuttingDown(wr http.ResponseWriter) {
+	writeError(wr, http.StatusServiceUnavailable, errCodeShuttingDown, errShuttingDown.Error(), nil)
+}
+
+// readiness를 내리고 실행 중인 job을 ShutdownGracePeriod 동안 기다린다.
+// 끝나지 않은 job과 실행하지 못한 대기 중인 job은 interrupted로 기록하고,
+// JobCheckpointFile에 저장해 재시작 후 재개할 수 있게 한다.
+func shutdown(server *http.Server) {
+	draining.Store(true)
+	logger.Info("shutting down", "grace_period", config.Env.ShutdownGracePeriod.String())
+
+	// 종료 중에는 대기열에서 꺼내 실행하지 않으므로 기다린 뒤에도 대기 중인 job이 남는다.
+	if !drainJobs(config.Env.ShutdownGracePeriod) {
+		logger.Warn("grace period expired with running jobs", "running", runningJobs())
+	}
+	interruptJobs()
+	if err := saveCheckpoint(config.Env.JobCheckpointFile); err != nil {
+		logger.Error("job checkpoint error", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
+	alive.Store(false)
+	logger.Info("shutdown complete")
+}
+
+// 실행 중인 job이 모두 끝나면 true를 반환한다.
+func drainJobs(grace time.Duration) bool {
+	deadline := time.Now().Add(grace)
+	for {
+		if runningJobs() == 0 {
+			return true
+		}
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(jobPollInterval)
+	}
+}
+
+func runningJobs() int {
+	return countJobs(nil, isRunning)
+}
+
+func interruptJobs() {
+	for _, j := range jobs.list() {
+		err := j.interrupt()
+		if errors.Is(err, errJobNotActive) {
+			continue
+		}
+		if err != nil {
+			logger.Warn("failed to stop rclone job", "job_id", j.id, "error", err)
+		}
+		logger.Info("migration job interrupted", "job_id", j.id)
+	}
+}
+
+// 재개할 수 있는 job을 저장한다. connection string에 인증 정보가 있으므로 암호화해 0600으로 만든다.
+// 저장할 job이 없으면 파일을 지운다.
+func saveCheckpoint(file string) error {
+	if file == "" {
+		return nil
+	}
+	var checkpoints []model.JobCheckpoint
+	for _, j := range jobs.list() {
+		if checkpoint, ok := j.checkpoint(); ok {
+			checkpoints = append(checkpoints, checkpoint)
+		}
+	}
+	if len(checkpoints) == 0 {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	byt, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encryptCheckpoint(byt)
+	if err != nil {
+		return err
+	}
+	if byt, err = json.Marshal(encrypted); err != nil {
+		return err
+	}
+	return os.WriteFile(file, byt, 0600)
+}
+
+// 이전 프로세스가 저장한 job을 다시 등록한다. 실행 중이던 job은 interrupted 상태로 재개를 기다린다.
+func restoreCheckpoint(file string) error {
+	if file == "" {
+		return nil
+	}
+	byt, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var encrypted model.EncryptedCheckpoint
+	if err := json.Unmarshal(byt, &encrypted); err != nil {
+		return err
+	}
+	if byt, err = decryptCheckpoint(encrypted); err != nil {
+		return err
+	}
+	var checkpoints []model.JobCheckpoint
+	if err := json.Unmarshal(byt, &checkpoints); err != nil {
+		return err
+	}
+	for _, checkpoint := range checkpoints {
+		j := jobs.restore(checkpoint)
+		logger.Info("migration job restored", "job_id", j.id, "state", checkpoint.Status.State)
+	}
+	return nil
+}
+
+// checkpoint를 암호화할 키. PrivateKeyDir에서 가장 오래 사용할 키를, 없으면 PrivateKey 설정의 키를 사용한다.
+func checkpointKey() (string, *rsa.PublicKey, error) {
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", nil, err
+	}
+	if keys := ring.activeKeys(time.Now()); len(keys) > 0 {
+		return keys[0].kid, &keys[0].key.PublicKey, nil
+	}
+	if config.Env.PrivateKey == "" {
+		return "", nil, errors.New("no RSA key to encrypt job checkpoint")
+	}
+	key, err := parsePrivateKey([]byte(config.Env.PrivateKey))
+	if err != nil {
+		return "", nil, err
+	}
+	return defaultKid, &key.PublicKey, nil
+}
+
+// 새 AES 키로 암호화하고 AES 키는 RSA-OAEP로 감싼다. kid를 associated data로 사용한다.
+func encryptCheckpoint(plainText []byte) (model.EncryptedCheckpoint, error) {
+	kid, publicKey, err := checkpointKey()
+	if err != nil {
+		return model.EncryptedCheckpoint{}, err
+	}
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		return model.EncryptedCheckpoint{}, err
+	}
+	cipherText, nonce, err := aesGcmEncrypt(plainText, aesKey, []byte(kid))
+	if err != nil {
+		return model.EncryptedCheckpoint{}, err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+	if err != nil {
+		return model.EncryptedCheckpoint{}, err
+	}
+	return model.EncryptedCheckpoint{
+		Kid:           kid,
+		EncryptedKey:  base64.StdEncoding.EncodeToString(encryptedKey),
+		IV:            base64.StdEncoding.EncodeToString(nonce),
+		EncryptedData: base64.StdEncoding.EncodeToString(cipherText),
+	}, nil
+}
+
+func decryptCheckpoint(checkpoint model.EncryptedCheckpoint) ([]byte, error) {
+	encryptedKey, err := base64.StdEncoding.DecodeString(checkpoint.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(checkpoint.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(checkpoint.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+	aesKey, err := rsaDecodeKid(checkpoint.Kid, encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint key: %w", err)
+	}
+	return aesGcmDecrypt(cipherText, aesKey, nonce, []byte(checkpoint.Kid))
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+)
+
+// job/stop을 호출할 때까지 끝나지 않는 rclone 작업을 흉내 낸다.
+func withStoppableRclone(t *testing.T) {
+	t.Helper()
+	var mu gosync.Mutex
+	stopped := false
+
+	oldInit, oldRPC, oldInterval := rcloneInitialize, rcloneRPC, jobPollInterval
+	t.Cleanup(func() {
+		stopJobRoutines(t)
+		rcloneInitialize, rcloneRPC, jobPollInterval = oldInit, oldRPC, oldInterval
+	})
+	rcloneInitialize = func() {}
+	rcloneRPC = func(method, in string) (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "sync/sync":
+			stopped = false
+			return `{"jobid":3}`, http.StatusOK
+		case "job/stop":
+			stopped = true
+			return `{}`, http.StatusOK
+		case "job/status":
+			if stopped {
+				return `{"id":3,"finished":true,"success":false,"error":"context canceled"}`, http.StatusOK
+			}
+			return `{"id":3,"finished":false}`, http.StatusOK
+		}
+		return `{}`, http.StatusOK
+	}
+	jobPollInterval = 5 * time.Millisecond
+}
+
+// 테스트 동안 빈 job 저장소와 종료 설정을 사용한다.
+func withShutdownState(t *testing.T, grace time.Duration) string {
+	t.Helper()
+	oldJobs, oldEnv := jobs, config.Env
+	jobs = newJobStore()
+	env := *oldEnv
+	env.ShutdownGracePeriod = grace
+	env.JobCheckpointFile = filepath.Join(t.TempDir(), "jobs.json")
+	config.Env = &env
+	keyDir := t.TempDir()
+	writeTestKey(t, keyDir, "checkpoint", "")
+	withTestKeyring(t, keyDir)
+	t.Cleanup(func() {
+		stopJobRoutines(t)
+		jobs, config.Env = oldJobs, oldEnv
+		draining.Store(false)
+		alive.Store(true)
+	})
+	return env.JobCheckpointFile
+}
+
+func TestShutdown_InterruptsAndRestoresJobs(t *testing.T) {
+	withAuditSink(t)
+	withStoppableRclone(t)
+	checkpointFile := withShutdownState(t, 20*time.Millisecond)
+
+	j := newRunningJob(jobs, "sync", "sync/sync", model.SyncRequest{SrcFs: ":s3,secret_access_key=SECRET:/src", DstFs: "/dst"})
+	j.setOwner("user-1")
+	if _, err := j.run(); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+
+	shutdown(&http.Server{})
+
+	status := j.snapshot()
+	if status.State != model.JobStateInterrupted || len(status.Actions) != 1 || status.Actions[0].Action != model.JobActionInterrupt {
+		t.Fatalf("expected interrupted job, got %+v", status)
+	}
+	if isAlive() == nil || isReady() == nil {
+		t.Fatal("expected probes to be down after shutdown")
+	}
+	info, err := os.Stat(checkpointFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected checkpoint file with 0600, got %v (err=%v)", info, err)
+	}
+	if byt, _ := os.ReadFile(checkpointFile); bytes.Contains(byt, []byte("SECRET")) || bytes.Contains(byt, []byte(j.id)) {
+		t.Fatalf("expected encrypted checkpoint, got %s", byt)
+	}
+
+	// 재시작 후 같은 id로 재개할 수 있다.
+	jobs = newJobStore()
+	draining.Store(false)
+	if err := restoreCheckpoint(checkpointFile); err != nil {
+		t.Fatalf("restore error: %v", err)
+	}
+	restored, ok := jobs.get(j.id)
+	if !ok {
+		t.Fatal("expected restored job")
+	}
+	if status := restored.snapshot(); status.State != model.JobStateInterrupted || status.Owner != "user-1" {
+		t.Fatalf("unexpected restored job: %+v", status)
+	}
+	if restored.request.SrcFs != ":s3,secret_access_key=SECRET:/src" || restored.group != "job/"+j.id {
+		t.Fatalf("unexpected restored request: %+v", restored.request)
+	}
+	if err := restored.resume(); err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	if state := restored.snapshot().State; state != model.JobStateRunning {
+		t.Fatalf("expected running job after resume, got %s", state)
+	}
+	if err := restored.cancel(); err != nil {
+		t.Fatalf("cancel error: %v", err)
+	}
+}
+
+func TestShutdown_DrainsFinishedJobs(t *testing.T) {
+	withAuditSink(t)
+	withRcloneResponses(t, map[string]string{
+		"sync/copy":  `{"jobid":4}`,
+		"job/status": `{"id":4,"finished":true,"success":true,"endTime":"2026-01-01T00:00:00Z"}`,
+		"core/stats": `{}`,
+	})
+	checkpointFile := withShutdownState(t, time.Second)
+	os.WriteFile(checkpointFile, []byte("[]"), 0600)
+
+	j := newRunningJob(jobs, "copy", "sync/copy", model.SyncRequest{})
+	if _, err := j.run(); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+
+	shutdown(&http.Server{})
+
+	if state := j.snapshot().State; state != model.JobStateSuccess {
+		t.Fatalf("expected job to finish during grace period, got %s", state)
+	}
+	// 재개할 job이 없으면 이전 checkpoint를 지운다.
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatalf("expected checkpoint file to be removed, got %v", err)
+	}
+}
+
+func TestShutdown_InterruptsQueuedJobsAfterDrain(t *testing.T) {
+	withAuditSink(t)
+	withRcloneResponses(t, nil)
+	// 종료를 시작하면 실행 중인 job이 끝난다.
+	rcloneRPC = func(method, in string) (string, int) {
+		switch method {
+		case "sync/sync":
+			return `{"jobid":1}`, http.StatusOK
+		case "job/status":
+			if draining.Load() {
+				return `{"id":1,"finished":true,"success":true}`, http.StatusOK
+			}
+			return `{"id":1,"finished":false}`, http.StatusOK
+		}
+		return `{}`, http.StatusOK
+	}
+	withScheduler(t, 1, 10, 0)
+	config.Env.ShutdownGracePeriod = time.Second
+	checkpointFile := config.Env.JobCheckpointFile
+
+	_, running := submitSync(t, "user-1", "")
+	_, queued := submitSync(t, "user-1", "")
+	if running.State != model.JobStateRunning || queued.State != model.JobStateQueued {
+		t.Fatalf("expected running and queued jobs, got %s %s", running.State, queued.State)
+	}
+
+	shutdown(&http.Server{})
+
+	a, _ := jobs.get(running.JobId)
+	b, _ := jobs.get(queued.JobId)
+	if a.state() != model.JobStateSuccess || b.state() != model.JobStateInterrupted {
+		t.Fatalf("expected a=success b=interrupted, got a=%s b=%s", a.state(), b.state())
+	}
+
+	jobs = newJobStore()
+	if err := restoreCheckpoint(checkpointFile); err != nil {
+		t.Fatalf("restore error: %v", err)
+	}
+	if restored, ok := jobs.get(queued.JobId); !ok || restored.state() != model.JobStateInterrupted {
+		t.Fatalf("expected queued job to be restored as interrupted, got %v", ok)
+	}
+	if _, ok := jobs.get(running.JobId); ok {
+		t.Fatal("expected finished job not to be saved")
+	}
+}
+
+func TestShutdown_RestoredJobKeepsAuditRecordAndTotals(t *testing.T) {
+	sink := withAuditSink(t)
+	withRcloneResponses(t, nil)
+	var mu gosync.Mutex
+	runs := 0
+	// 재시작 전 실행은 100 byte, 재시작 후 실행은 40 byte를 전송한다.
+	rcloneRPC = func(method, in string) (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "sync/sync":
+			runs++
+			return fmt.Sprintf(`{"jobid":%d}`, runs), http.StatusOK
+		case "core/stats":
+			if runs == 1 {
+				return `{"bytes":100,"transfers":2}`, http.StatusOK
+			}
+			return `{"bytes":40,"transfers":1}`, http.StatusOK
+		case "job/status":
+			if runs == 2 {
+				return `{"id":2,"finished":true,"success":true}`, http.StatusOK
+			}
+			return `{"id":1,"finished":false}`, http.StatusOK
+		}
+		return `{}`, http.StatusOK
+	}
+	checkpointFile := withShutdownState(t, 0)
+
+	j := newRunningJob(jobs, "sync", "sync/sync", model.SyncRequest{SrcFs: "/src", DstFs: "/dst"})
+	j.setAuditRecord(model.AuditRecord{Operation: "sync", Caller: &model.AuditCaller{Subject: "user-1"}})
+	if _, err := j.run(); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	shutdown(&http.Server{})
+
+	jobs = newJobStore()
+	draining.Store(false)
+	if err := restoreCheckpoint(checkpointFile); err != nil {
+		t.Fatalf("restore error: %v", err)
+	}
+	restored, _ := jobs.get(j.id)
+	if err := restored.resume(); err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	waitForState(t, restored, model.JobStateSuccess)
+
+	records := sink.snapshot()
+	if len(records) != 1 || records[0].JobId != j.id || records[0].Caller == nil || records[0].Caller.Subject != "user-1" || records[0].Bytes != 140 || records[0].Objects != 3 {
+		t.Fatalf("expected completion audit record with totals before the restart, got %+v", records)
+	}
+}
+
+func TestShutdown_RejectsNewJobs(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	withAuditSink(t)
+	withShutdownState(t, time.Second)
+	draining.Store(true)
+
+	if err := isReady(); err == nil {
+		t.Fatal("expected readiness to be down while draining")
+	}
+	if err := isAlive(); err != nil {
+		t.Fatalf("expected liveness to stay up while draining: %v", err)
+	}
+
+	body, _ := json.Marshal(model.SyncConfig{
+		Src: model.StorageConfig{StorageType: "local", Bucket: "/src"},
+		Dst: model.StorageConfig{StorageType: "local", Bucket: "/dst"},
+	})
+	w := httptest.NewRecorder()
+	sync(w, httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	if resp := decodeResponse(t, w.Body, nil); resp.Error == nil || resp.Error.Code != errCodeShuttingDown {
+		t.Fatalf("expected %s, got %+v", errCodeShuttingDown, resp.Error)
+	}
+
+	w = httptest.NewRecorder()
+	resumeJob(w, httptest.NewRequest(http.MethodPost, "/v1/migration/jobs/any/resume", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for resume, got %d", w.Code)
+	}
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs/config/obscure"
+	"golang.org/x/crypto/ssh"
+)
+
+// 지원하는 storageType
+const (
+	storageTypeS3        = "s3"
+	storageTypeAzureBlob = "azureblob"
+	storageTypeGcs       = "google cloud storage"
+	storageTypeSwift     = "swift"
+	storageTypeSftp      = "sftp"
+	storageTypeLocal     = "local"
+)
+
+// 스토리지 설정을 검증하고 rclone connection string을 만든다.
+// extra는 요청의 tuning에서 만든 backend 옵션이다.
+func storageFs(storage model.StorageConfig, extra ...fsOption) (string, error) {
+	var backend string
+	var options []fsOption
+	var err error
+
+	switch strings.ToLower(storage.StorageType) {
+	case storageTypeS3:
+		backend = "s3"
+		options, err = s3Options(storage)
+	case storageTypeAzureBlob:
+		backend = "azureblob"
+		options, err = azureBlobOptions(storage.AzureBlob)
+	case storageTypeGcs, "gcs":
+		backend = "gcs"
+		options, err = gcsOptions(storage.Gcs)
+	case storageTypeSwift:
+		backend = "swift"
+		options, err = swiftOptions(storage.Swift)
+	case storageTypeSftp:
+		backend = "sftp"
+		options, err = sftpOptions(storage.Sftp)
+	case storageTypeLocal:
+		backend = "local"
+		storage.Bucket, err = localPath(storage.Bucket)
+	default:
+		err = fmt.Errorf("unsupported storageType %q", storage.StorageType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	builder, err := newFsBuilder(backend)
+	if err != nil {
+		return "", err
+	}
+	for _, option := range append(options, extra...) {
+		builder.set(option.key, option.value)
+	}
+	return builder.build(storage.Bucket)
+}
+
+// local storage는 LocalStorageEnabled일 때 LocalStorageRoot 아래 경로만 사용할 수 있다.
+// 상대 경로는 LocalStorageRoot 기준이다.
+func localPath(bucket string) (string, error) {
+	if !config.Env.LocalStorageEnabled {
+		return "", errors.New("local storage is disabled")
+	}
+	if bucket == "" {
+		return "", errors.New("local storage requires a path in bucket")
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(bucket), "/") {
+		if segment == ".." {
+			return "", errors.New("local storage path must not contain ..")
+		}
+	}
+	root := filepath.Clean(config.Env.LocalStorageRoot)
+	path := filepath.Clean(bucket)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("local storage path must be under %s", root)
+	}
+	return path, nil
+}
+
+// bucket 목록 조회는 object storage는 최상위를, sftp와 local은 bucket에 지정한 경로를 조회한다.
+func listFs(storage model.StorageConfig) (string, error) {
+	switch strings.ToLower(storage.StorageType) {
+	case storageTypeSftp, storageTypeLocal:
+	default:
+		storage.Bucket = ""
+	}
+	return storageFs(storage)
+}
+
+// rclone s3 backend가 지원하는 provider. 소문자 입력도 rclone 이름으로 바꿔 전달한다.
+var s3Providers = []string{
+	"AWS", "Alibaba", "ArvanCloud", "Ceph", "ChinaMobile", "Cloudflare", "DigitalOcean",
+	"Dreamhost", "GCS", "HuaweiOBS", "IBMCOS", "IDrive", "IONOS", "Leviia", "Liara",
+	"Linode", "LyveCloud", "Magalu", "Minio", "Netease", "Outscale", "Petabox", "Qiniu",
+	"RackCorp", "Rclone", "Scaleway", "SeaweedFS", "Selectel", "StackPath", "Storj",
+	"Synology", "TencentCOS", "Wasabi", "Other",
+}
+
+// s3 canned ACL
+var s3Acls = []string{
+	"private", "public-read", "public-read-write", "authenticated-read",
+	"bucket-owner-read", "bucket-owner-full-control",
+}
+
+func s3Options(storage model.StorageConfig) ([]fsOption, error) {
+	if (storage.AccessKeyId == "") != (storage.SecretAccessKey == "") {
+		return nil, errors.New("s3 storage requires both accessKeyId and secretAccessKey")
+	}
+	options := []fsOption{
+		{"access_key_id", storage.AccessKeyId},
+		{"secret_access_key", storage.SecretAccessKey},
+		{"endpoint", storage.Endpoint},
+	}
+
+	if storage.Provider != "" {
+		provider, ok := lookupFold(s3Providers, storage.Provider)
+		if !ok {
+			return nil, fmt.Errorf("unsupported s3 provider %q", storage.Provider)
+		}
+		options = append(options, fsOption{"provider", provider})
+	}
+	if storage.Acl != "" {
+		acl, ok := lookupFold(s3Acls, storage.Acl)
+		if !ok {
+			return nil, fmt.Errorf("unsupported s3 acl %q", storage.Acl)
+		}
+		options = append(options, fsOption{"acl", acl})
+	}
+	options = append(options,
+		fsOption{"region", storage.Region},
+		fsOption{"location_constraint", storage.LocationConstraint},
+	)
+	if storage.ForcePathStyle != nil {
+		options = append(options, fsOption{"force_path_style", strconv.FormatBool(*storage.ForcePathStyle)})
+	}
+	if storage.V2Auth != nil {
+		options = append(options, fsOption{"v2_auth", strconv.FormatBool(*storage.V2Auth)})
+	}
+	return options, nil
+}
+
+func lookupFold(values []string, value string) (string, bool) {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func azureBlobOptions(config *model.AzureBlobConfig) ([]fsOption, error) {
+	if config == nil {
+		return nil, errors.New("azureblob storage requires azureblob settings")
+	}
+	switch {
+	case config.SasUrl != "":
+		return []fsOption{{"sas_url", config.SasUrl}}, nil
+	case config.Account != "" && config.Key != "":
+		return []fsOption{{"account", config.Account}, {"key", config.Key}}, nil
+	}
+	return nil, errors.New("azureblob storage requires account and key, or sasUrl")
+}
+
+func gcsOptions(config *model.GcsConfig) ([]fsOption, error) {
+	if config == nil || config.ServiceAccountCredentials == "" {
+		return nil, errors.New("gcs storage requires serviceAccountCredentials")
+	}
+	// 줄바꿈이 포함된 JSON 파일 내용도 한 줄로 만들어 전달한다.
+	var credentials bytes.Buffer
+	if err := json.Compact(&credentials, []byte(config.ServiceAccountCredentials)); err != nil {
+		return nil, errors.New("gcs serviceAccountCredentials is not valid JSON")
+	}
+	return []fsOption{
+		{"service_account_credentials", credentials.String()},
+		{"project_number", config.ProjectNumber},
+	}, nil
+}
+
+func swiftOptions(config *model.SwiftConfig) ([]fsOption, error) {
+	if config == nil || config.AuthUrl == "" || config.User == "" || config.Key == "" {
+		return nil, errors.New("swift storage requires authUrl, user and key")
+	}
+	options := []fsOption{
+		{"auth", config.AuthUrl},
+		{"user", config.User},
+		{"key", config.Key},
+		{"tenant", config.Tenant},
+		{"domain", config.Domain},
+		{"region", config.Region},
+	}
+	if config.AuthVersion != 0 {
+		options = append(options, fsOption{"auth_version", strconv.Itoa(config.AuthVersion)})
+	}
+	return options, nil
+}
+
+func sftpOptions(sftp *model.SftpConfig) ([]fsOption, error) {
+	if sftp == nil || sftp.Host == "" || sftp.User == "" {
+		return nil, errors.New("sftp storage requires host and user")
+	}
+	if sftp.Pass == "" && sftp.KeyPem == "" {
+		return nil, errors.New("sftp storage requires pass or keyPem")
+	}
+	if sftp.KnownHosts == "" && !config.Env.SftpInsecureHostKey {
+		return nil, errors.New("sftp storage requires knownHosts")
+	}
+	options := []fsOption{
+		{"host", sftp.Host},
+		{"user", sftp.User},
+	}
+	if sftp.Port != 0 {
+		options = append(options, fsOption{"port", strconv.Itoa(sftp.Port)})
+	}
+	if sftp.Pass != "" {
+		// rclone은 sftp 비밀번호를 obscure된 값으로 받는다.
+		pass, err := obscure.Obscure(sftp.Pass)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, fsOption{"pass", pass})
+	}
+	if sftp.KeyPem != "" {
+		// key_pem은 줄바꿈을 \n으로 바꾼 한 줄 값이어야 한다.
+		keyPem := strconv.Quote(sftp.KeyPem)
+		options = append(options, fsOption{"key_pem", keyPem[1 : len(keyPem)-1]})
+	}
+	if sftp.KnownHosts != "" {
+		file, err := knownHostsFile(sftp.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, fsOption{"known_hosts_file", file})
+	}
+	return options, nil
+}
+
+// sftp known_hosts 파일을 저장하는 디렉토리
+var knownHostsDir = filepath.Join(os.TempDir(), "kps-migration-api-known-hosts")
+
+// rclone은 known_hosts를 파일로만 받으므로 내용을 검증해 파일로 저장한다.
+// host key는 비밀이 아니므로 내용의 hash를 파일 이름으로 하고 같은 내용이면 다시 사용한다.
+func knownHostsFile(knownHosts string) (string, error) {
+	rest := []byte(knownHosts)
+	for entries := 0; ; entries++ {
+		var err error
+		_, _, _, _, rest, err = ssh.ParseKnownHosts(rest)
+		if err == io.EOF && entries > 0 {
+			break
+		}
+		if err != nil {
+			return "", errors.New("sftp knownHosts must be OpenSSH known_hosts lines")
+		}
+	}
+
+	sum := sha256.Sum256([]byte(knownHosts))
+	file := filepath.Join(knownHostsDir, hex.EncodeToString(sum[:]))
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+	if err := os.MkdirAll(knownHostsDir, 0700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(knownHostsDir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(knownHosts); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return file, os.Rename(tmp.Name(), file)
+}
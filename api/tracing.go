@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "kps-migration-api"
+
+// TracingExporter 값
+const (
+	tracingExporterNone   = "none"
+	tracingExporterOtlp   = "otlp"
+	tracingExporterStdout = "stdout"
+)
+
+// 전역 TracerProvider를 따르므로 SetupTracing 전에 만든 span도 설정 후에는 기록된다.
+var tracer = otel.Tracer(tracerName)
+
+// W3C trace context를 전파하고 TracingExporter에 따라 span을 내보낸다.
+// 반환한 함수는 종료 시 남은 span을 내보낸다.
+func SetupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Env.TracingExporter {
+	case "", tracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case tracingExporterOtlp:
+		// endpoint가 비어 있으면 OTEL_EXPORTER_OTLP_ENDPOINT 또는 localhost:4318을 사용한다.
+		var options []otlptracehttp.Option
+		if config.Env.TracingEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(config.Env.TracingEndpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case tracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		err = fmt.Errorf("unsupported TracingExporter %q", config.Env.TracingExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingSampleRatio()))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("cp-migration-api"))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// TracingSampleRatio (0~1, 기본 1). 요청에 trace context가 있으면 호출한 쪽의 sampling을 따른다.
+func tracingSampleRatio() float64 {
+	ratio, err := strconv.ParseFloat(config.Env.TracingSampleRatio, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// route template을 span 이름으로 사용한다. (예: GET /v1/migration/jobs/{id})
+func tracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					return r.Method + " " + template
+				}
+			}
+			return r.Method
+		}),
+	)
+}
+
+func callRclone(ctx context.Context, method string, input string) (string, int) {
+	return traceRclone(ctx, method, func() (string, int) {
+		return rcloneRPC(method, input)
+	})
+}
+
+// rclone rc 호출을 span으로 기록한다. 오류 메시지에는 인증 정보가 있을 수 있어 status만 남긴다.
+func traceRclone(ctx context.Context, method string, rpc func() (string, int)) (string, int) {
+	_, span := tracer.Start(ctx, "rclone "+method, trace.WithAttributes(attribute.String("rclone.method", method)))
+	defer span.End()
+
+	out, status := rpc()
+	span.SetAttributes(attribute.Int("rclone.status", status))
+	if status != http.StatusOK {
+		span.SetStatus(codes.Error, "rclone returned status "+strconv.Itoa(status))
+		// 오류 메시지에는 connection string의 인증 정보가 그대로 있을 수 있어 분류만 남긴다.
+		_, code := classifyRcloneError(rcloneErrorMessage(out, status), status)
+		logger.WarnContext(ctx, "rclone call failed", "method", method, "status", status, "error_code", code)
+	}
+	return out, status
+}
+
+// 요청 처리 단계(decode 등)를 span으로 기록한다.
+func traceStep(ctx context.Context, name string, fn func() error) error {
+	_, span := tracer.Start(ctx, name)
+	defer span.End()
+
+	err := fn()
+	if err != nil {
+		span.SetStatus(codes.Error, name+" failed")
+	}
+	return err
+}
+
+// 요청보다 오래 실행되는 job의 span. 요청 span의 자식으로 시작하고 job이 끝나면 종료한다.
+func (j *job) startTrace(ctx context.Context) {
+	ctx, span := tracer.Start(context.WithoutCancel(ctx), "migration job", trace.WithAttributes(
+		attribute.String("job.id", j.id),
+		attribute.String("job.operation", j.status.Operation),
+		attribute.Bool("job.dry_run", j.status.DryRun),
+	))
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.traceCtx = ctx
+	j.span = span
+}
+
+func (j *job) traceContext() context.Context {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.traceCtx == nil {
+		return context.Background()
+	}
+	return j.traceCtx
+}
+
+func (j *job) endTrace(status model.JobStatus, progress model.JobProgress) {
+	j.mu.Lock()
+	span := j.span
+	j.mu.Unlock()
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("job.state", string(status.State)),
+		attribute.Int64("job.bytes", progress.Bytes),
+		attribute.Int64("job.objects", progress.Transfers),
+		attribute.Int64("job.deletes", progress.Deletes),
+	)
+	if status.State == model.JobStateError {
+		span.SetStatus(codes.Error, status.ErrorCode)
+	}
+	span.End()
+}
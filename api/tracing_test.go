@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// 테스트 동안 span을 메모리에 기록한다.
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	oldProvider, oldPropagator, oldTracer := otel.GetTracerProvider(), otel.GetTextMapPropagator(), tracer
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	// 전역 tracer는 처음 설정된 provider에만 위임하므로 테스트마다 다시 만든다.
+	tracer = provider.Tracer(tracerName)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(oldProvider)
+		otel.SetTextMapPropagator(oldPropagator)
+		tracer = oldTracer
+	})
+	return recorder
+}
+
+func endedSpan(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracing_SyncRequestSpans(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	withAuthDisabled(t)
+	allowLocalStorage(t, "/")
+	recorder := withSpanRecorder(t)
+	sink := withAuditSink(t)
+	withRcloneResponses(t, map[string]string{
+		"sync/sync":  `{"jobid":11}`,
+		"job/status": `{"id":11,"finished":true,"success":true,"endTime":"2026-01-01T00:00:00Z"}`,
+		"core/stats": `{"bytes":512,"transfers":1}`,
+	})
+
+	body, _ := json.Marshal(model.SyncConfig{
+		Src: model.StorageConfig{StorageType: "local", Bucket: "/src"},
+		Dst: model.StorageConfig{StorageType: "local", Bucket: "/dst"},
+	})
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body))
+	req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	var accepted model.JobAccepted
+	decodeResponse(t, w.Body, &accepted)
+	t.Cleanup(func() { jobs.remove(accepted.JobId) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	// 감사 기록은 job 종료 처리의 마지막 단계다.
+	for len(sink.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, name := range []string{"POST /v1/migration/sync/sync", "decode payload", "build fs", "rclone sync/sync", "migration job"} {
+		span := endedSpan(recorder, name)
+		if span == nil {
+			t.Fatalf("expected span %q", name)
+		}
+		// UI의 traceparent를 이어받는다.
+		if got := span.SpanContext().TraceID().String(); got != traceId {
+			t.Fatalf("expected trace id %s for %q, got %s", traceId, name, got)
+		}
+	}
+
+	jobSpan := endedSpan(recorder, "migration job")
+	attributes := map[string]string{}
+	for _, kv := range jobSpan.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attributes["job.id"] != accepted.JobId || attributes["job.state"] != "success" || attributes["job.bytes"] != "512" {
+		t.Fatalf("unexpected job span attributes: %v", attributes)
+	}
+}
+
+func TestTracing_RcloneErrorStatus(t *testing.T) {
+	recorder := withSpanRecorder(t)
+
+	traceRclone(t.Context(), "operations/list", func() (string, int) {
+		return `{"error":"boom"}`, http.StatusInternalServerError
+	})
+
+	span := endedSpan(recorder, "rclone operations/list")
+	if span == nil {
+		t.Fatal("expected rclone span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected error status, got %v", span.Status())
+	}
+}
+
+func TestTracing_FailedStartEndsJobSpan(t *testing.T) {
+	config.Env.IsEncryption = "false"
+	withAuthDisabled(t)
+	allowLocalStorage(t, "/")
+	recorder := withSpanRecorder(t)
+	withAuditSink(t)
+	withRcloneResponses(t, map[string]string{})
+
+	body, _ := json.Marshal(model.SyncConfig{
+		Src: model.StorageConfig{StorageType: "local", Bucket: "/src"},
+		Dst: model.StorageConfig{StorageType: "local", Bucket: "/dst"},
+	})
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/migration/sync/sync", bytes.NewReader(body)))
+	if w.Code == http.StatusAccepted {
+		t.Fatalf("expected rclone failure, got %d", w.Code)
+	}
+
+	span := endedSpan(recorder, "migration job")
+	if span == nil {
+		t.Fatal("expected job span to be ended")
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected error status, got %v", span.Status())
+	}
+}
+
+func TestTracing_SetupExporter(t *testing.T) {
+	oldEnv := config.Env
+	t.Cleanup(func() { config.Env = oldEnv })
+	env := *oldEnv
+	config.Env = &env
+
+	env.TracingExporter = "zipkin"
+	if _, err := SetupTracing(t.Context()); err == nil {
+		t.Fatal("expected error for unsupported exporter")
+	}
+
+	env.TracingExporter = ""
+	shutdown, err := SetupTracing(t.Context())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// rclone s3 backend의 최소 chunk_size
+const minS3ChunkSize = 5 << 20
+
+// 요청의 tuning을 rclone _config와 대상 s3 backend 옵션으로 바꾼다.
+// 서버 최대값(MaxTransfers 등)을 넘는 값은 오류로 반환한다.
+func buildTuning(tuning *model.TuningConfig, dst model.StorageConfig) (*model.RcloneConfig, []fsOption, error) {
+	if tuning == nil {
+		tuning = &model.TuningConfig{}
+	}
+
+	for _, count := range []struct {
+		name  string
+		value int
+		max   int
+	}{
+		{"transfers", tuning.Transfers, config.Env.MaxTransfers},
+		{"checkers", tuning.Checkers, config.Env.MaxCheckers},
+		{"multiThreadStreams", tuning.MultiThreadStreams, config.Env.MaxMultiThreadStreams},
+		{"uploadConcurrency", tuning.UploadConcurrency, config.Env.MaxUploadConcurrency},
+	} {
+		if count.value < 0 {
+			return nil, nil, fmt.Errorf("%s must not be negative, got %d", count.name, count.value)
+		}
+		if count.max > 0 && count.value > count.max {
+			return nil, nil, fmt.Errorf("%s must be at most %d, got %d", count.name, count.max, count.value)
+		}
+	}
+	if _, err := tuningSize("bufferSize", tuning.BufferSize, config.Env.MaxBufferSize); err != nil {
+		return nil, nil, err
+	}
+	chunkSize, err := tuningSize("chunkSize", tuning.ChunkSize, config.Env.MaxChunkSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if chunkSize > 0 && chunkSize < minS3ChunkSize {
+		return nil, nil, fmt.Errorf("chunkSize must be at least %s, got %q", fs.SizeSuffix(minS3ChunkSize), tuning.ChunkSize)
+	}
+
+	var options []fsOption
+	if tuning.ChunkSize != "" || tuning.UploadConcurrency > 0 {
+		if strings.ToLower(dst.StorageType) != storageTypeS3 {
+			return nil, nil, errors.New("chunkSize and uploadConcurrency require an s3 dst")
+		}
+		options = append(options, fsOption{"chunk_size", tuning.ChunkSize})
+		if tuning.UploadConcurrency > 0 {
+			options = append(options, fsOption{"upload_concurrency", strconv.Itoa(tuning.UploadConcurrency)})
+		}
+	}
+
+	rcloneConfig := &model.RcloneConfig{
+		Transfers:          tuning.Transfers,
+		Checkers:           tuning.Checkers,
+		BufferSize:         tuning.BufferSize,
+		MultiThreadStreams: tuning.MultiThreadStreams,
+		MultiThreadSet:     tuning.MultiThreadStreams > 0,
+	}
+
+	bandwidth, err := tuningSize("bwLimit", tuning.BwLimit, config.Env.MaxJobBandwidth)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bandwidth == 0 {
+		bandwidth, _ = config.ParseSize(config.Env.MaxJobBandwidth)
+	}
+	if bandwidth > 0 {
+		// rclone의 bwlimit는 프로세스 전체에 적용되므로 job 단위로는 파일별 제한(bwlimit_file)을 사용한다.
+		// 동시에 전송하는 파일 수로 나눠 job 전체가 bandwidth를 넘지 않게 한다.
+		transfers := int64(tuning.Transfers)
+		if transfers == 0 {
+			transfers = int64(fs.GetConfig(context.Background()).Transfers)
+		}
+		rcloneConfig.BwLimitFile = strconv.FormatInt(max(bandwidth/transfers, 1), 10) + "B"
+	}
+	return rcloneConfig, options, nil
+}
+
+// 크기 값을 byte로 바꾸고 서버 최대값을 넘지 않는지 확인한다.
+func tuningSize(name string, value string, maxValue string) (int64, error) {
+	size, err := config.ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	// 최대값은 설정을 읽을 때 검증했다.
+	limit, _ := config.ParseSize(maxValue)
+	if limit > 0 && size > limit {
+		return 0, fmt.Errorf("%s must be at most %s, got %q", name, maxValue, value)
+	}
+	return size, nil
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"kps-migration-api/config"
+	"kps-migration-api/model"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+func withTuningLimits(t *testing.T, bandwidth string) {
+	t.Helper()
+	oldEnv := config.Env
+	t.Cleanup(func() { config.Env = oldEnv })
+	env := *oldEnv
+	env.IsEncryption = "false"
+	env.MaxTransfers = 16
+	env.MaxCheckers = 32
+	env.MaxMultiThreadStreams = 8
+	env.MaxUploadConcurrency = 8
+	env.MaxBufferSize = "64M"
+	env.MaxChunkSize = "128M"
+	env.MaxJobBandwidth = bandwidth
+	config.Env = &env
+}
+
+var s3Dst = model.StorageConfig{StorageType: "s3", Endpoint: "http://s3.local", AccessKeyId: "AK", SecretAccessKey: "SK", Bucket: "dst"}
+
+func TestBuildTuning_AppliesToRclone(t *testing.T) {
+	withTuningLimits(t, "")
+	rcloneConfig, options, err := buildTuning(&model.TuningConfig{
+		Transfers:          8,
+		Checkers:           16,
+		BufferSize:         "32M",
+		MultiThreadStreams: 4,
+		ChunkSize:          "64M",
+		UploadConcurrency:  6,
+		BwLimit:            "80M",
+	}, s3Dst)
+	if err != nil {
+		t.Fatalf("buildTuning error: %v", err)
+	}
+
+	// rclone이 _config를 읽는 것과 같은 방식으로 확인한다.
+	byt, _ := json.Marshal(model.SyncRequest{Config: rcloneConfig})
+	var params rc.Params
+	json.Unmarshal(byt, &params)
+	ci := *fs.GetConfig(context.Background())
+	if err := params.GetStruct("_config", &ci); err != nil {
+		t.Fatalf("rclone cannot read _config: %v", err)
+	}
+	if ci.Transfers != 8 || ci.Checkers != 16 || ci.BufferSize != 32*fs.Mebi || ci.MultiThreadStreams != 4 || !ci.MultiThreadSet {
+		t.Fatalf("unexpected rclone config: transfers=%d checkers=%d buffer=%s streams=%d", ci.Transfers, ci.Checkers, ci.BufferSize, ci.MultiThreadStreams)
+	}
+	// job 대역폭을 동시에 전송하는 파일 수로 나눈다.
+	if len(ci.BwLimitFile) != 1 || ci.BwLimitFile[0].Bandwidth.Tx != 10*fs.Mebi {
+		t.Fatalf("expected 10M per file, got %s", ci.BwLimitFile)
+	}
+
+	builder, _ := newFsBuilder("s3")
+	for _, option := range options {
+		builder.set(option.key, option.value)
+	}
+	dstFs, err := builder.build("dst")
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	parsed, _ := fspath.Parse(dstFs)
+	if parsed.Config["chunk_size"] != "64M" || parsed.Config["upload_concurrency"] != "6" {
+		t.Fatalf("unexpected backend options: %v", parsed.Config)
+	}
+}
+
+func TestBuildTuning_ServerLimits(t *testing.T) {
+	withTuningLimits(t, "40M")
+
+	for _, tc := range []struct {
+		tuning model.TuningConfig
+		dst    model.StorageConfig
+		errMsg string
+	}{
+		{model.TuningConfig{Transfers: 64}, s3Dst, "transfers must be at most 16"},
+		{model.TuningConfig{Checkers: -1}, s3Dst, "checkers must not be negative"},
+		{model.TuningConfig{BufferSize: "1G"}, s3Dst, "bufferSize must be at most 64M"},
+		{model.TuningConfig{ChunkSize: "1M"}, s3Dst, "chunkSize must be at least"},
+		{model.TuningConfig{ChunkSize: "abc"}, s3Dst, "chunkSize:"},
+		{model.TuningConfig{BwLimit: "100M"}, s3Dst, "bwLimit must be at most 40M"},
+		{model.TuningConfig{BwLimit: "off"}, s3Dst, "bwLimit:"},
+		{model.TuningConfig{UploadConcurrency: 4}, model.StorageConfig{StorageType: "local", Bucket: "/dst"}, "require an s3 dst"},
+	} {
+		if _, _, err := buildTuning(&tc.tuning, tc.dst); err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Fatalf("%+v: expected error %q, got %v", tc.tuning, tc.errMsg, err)
+		}
+	}
+
+	// 요청에 대역폭이 없어도 서버 상한을 적용한다.
+	rcloneConfig, _, err := buildTuning(nil, s3Dst)
+	if err != nil {
+		t.Fatalf("buildTuning error: %v", err)
+	}
+	transfers := int64(fs.GetConfig(context.Background()).Transfers)
+	if rcloneConfig.BwLimitFile != strconv.FormatInt(40*int64(fs.Mebi)/transfers, 10)+"B" {
+		t.Fatalf("expected default bandwidth limit, got %q", rcloneConfig.BwLimitFile)
+	}
+}
+
+func TestStartMigration_Tuning(t *testing.T) {
+	withAuditSink(t)
+	allowLocalStorage(t, "/")
+	withTuningLimits(t, "")
+	var requests []string
+	withRcloneJobs(t)
+	mock := rcloneRPC
+	rcloneRPC = func(method, in string) (string, int) {
+		if method == "sync/copy" {
+			requests = append(requests, in)
+		}
+		return mock(method, in)
+	}
+	withScheduler(t, 4, 0, 0)
+
+	submit := func(tuning model.TuningConfig) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.SyncConfig{
+			Src:    model.StorageConfig{StorageType: "local", Bucket: "/src"},
+			Dst:    s3Dst,
+			Tuning: &tuning,
+		})
+		w := httptest.NewRecorder()
+		copy(w, httptest.NewRequest(http.MethodPost, "/v1/migration/sync/copy", bytes.NewReader(body)))
+		return w
+	}
+
+	if w := submit(model.TuningConfig{Transfers: 12, ChunkSize: "16M"}); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected one rclone call, got %d", len(requests))
+	}
+	var request model.SyncRequest
+	json.Unmarshal([]byte(requests[0]), &request)
+	if request.Config == nil || request.Config.Transfers != 12 || !strings.Contains(request.DstFs, `chunk_size="16M"`) {
+		t.Fatalf("expected tuning in rclone request, got %s", requests[0])
+	}
+
+	w := submit(model.TuningConfig{Transfers: 100})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if resp := decodeResponse(t, w.Body, nil); resp.Error == nil || resp.Error.Code != errCodeInvalidTuning {
+		t.Fatalf("expected %s, got %+v", errCodeInvalidTuning, resp.Error)
+	}
+}
@@ -1,39 +1,444 @@
 package config
 
 import (
-	"github.com/gofiber/fiber/v2/log"
-	"github.com/spf13/viper"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
 var Env *envConfigs
 
 func init() {
-	Env = loadEnvVariables()
+	var args []string
+	// go test 인자는 서버 flag가 아니다.
+	if os.Getenv("UNIT_TEST") != "1" {
+		args = os.Args[1:]
+	}
+	env, err := loadEnvVariables(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(1)
+	}
+	Env = env
 }
 
 type envConfigs struct {
 	HmacKey      string `mapstructure:"HmacKey"`
 	PrivateKey   string `mapstructure:"PrivateKey"`
 	IsEncryption string `mapstructure:"IsEncryption"`
+	// kid별 RSA 키(<kid>.pem, <kid>.retire) 디렉토리
+	PrivateKeyDir string `mapstructure:"PrivateKeyDir"`
+	// IsEncryption이 true일 때 응답도 요청의 AES 키로 암호화한다.
+	IsResponseEncryption string `mapstructure:"IsResponseEncryption"`
+	// 암호화 요청 timestamp 허용 범위 (duration, 기본 5m, 30s)
+	PayloadMaxAge  string `mapstructure:"PayloadMaxAge"`
+	PayloadMaxSkew string `mapstructure:"PayloadMaxSkew"`
+	// OIDC access token 검증. AuthJwksUrl 또는 AuthPublicKeyFile이 없으면 모든 요청을 거부한다.
+	// AuthDisabled가 true이면 검증하지 않는다 (개발용).
+	AuthDisabled      bool   `mapstructure:"AuthDisabled"`
+	AuthJwksUrl       string `mapstructure:"AuthJwksUrl"`
+	AuthPublicKeyFile string `mapstructure:"AuthPublicKeyFile"`
+	AuthIssuer        string `mapstructure:"AuthIssuer"`
+	AuthAudience      string `mapstructure:"AuthAudience"`
+	// swagger, metrics를 토큰 없이 호출할 수 있게 한다 (기본 false).
+	AuthPublicSwagger bool `mapstructure:"AuthPublicSwagger"`
+	AuthPublicMetrics bool `mapstructure:"AuthPublicMetrics"`
+	// role, group별 API 권한 정책 파일. 비어 있으면 권한을 확인하지 않는다.
+	RbacPolicyFile string `mapstructure:"RbacPolicyFile"`
+	// 감사 로그 파일. 비어 있거나 stdout이면 stdout에 기록한다.
+	AuditLogFile string `mapstructure:"AuditLogFile"`
+	// OpenTelemetry span exporter (none, otlp, stdout), OTLP/HTTP endpoint URL, sampling 비율(0~1)
+	TracingExporter    string `mapstructure:"TracingExporter"`
+	TracingEndpoint    string `mapstructure:"TracingEndpoint"`
+	TracingSampleRatio string `mapstructure:"TracingSampleRatio"`
+	// 로그 레벨 (debug, info, warn, error). 기본 info
+	LogLevel string `mapstructure:"LogLevel"`
+
+	// 서버 listen 주소 (기본 :8901)
+	ListenAddress string `mapstructure:"ListenAddress"`
+	// http.Server timeout (기본 10s, 10s, 120s)
+	ReadTimeout  time.Duration `mapstructure:"ReadTimeout"`
+	WriteTimeout time.Duration `mapstructure:"WriteTimeout"`
+	IdleTimeout  time.Duration `mapstructure:"IdleTimeout"`
+	// route별 처리 timeout. "<route template>=<duration>"을 ,로 구분한다.
+	RouteTimeouts string `mapstructure:"RouteTimeouts"`
+	// CORS 허용 origin, method, header (,로 구분)
+	CorsAllowedOrigins []string `mapstructure:"CorsAllowedOrigins"`
+	CorsAllowedMethods []string `mapstructure:"CorsAllowedMethods"`
+	CorsAllowedHeaders []string `mapstructure:"CorsAllowedHeaders"`
+	// 요청 본문 최대 크기 (byte, 기본 1MiB)
+	MaxRequestBodySize int64 `mapstructure:"MaxRequestBodySize"`
+	// 둘 다 있으면 HTTPS로 listen 한다.
+	TlsCertFile string `mapstructure:"TlsCertFile"`
+	TlsKeyFile  string `mapstructure:"TlsKeyFile"`
+	// 기능 사용 여부 (기본 true)
+	SwaggerEnabled bool `mapstructure:"SwaggerEnabled"`
+	MetricsEnabled bool `mapstructure:"MetricsEnabled"`
+	// 종료 신호 후 실행 중인 job을 기다리는 시간 (기본 25s)
+	ShutdownGracePeriod time.Duration `mapstructure:"ShutdownGracePeriod"`
+	// 종료 시 끝나지 않은 job을 암호화해 저장하고 시작 시 다시 등록하는 파일. 비어 있으면 저장하지 않는다.
+	JobCheckpointFile string `mapstructure:"JobCheckpointFile"`
+	// 실행 중이거나 대기 중인 job이 이 수 이상이면 readiness를 내린다. 0이면 확인하지 않는다.
+	ReadinessMaxActiveJobs int `mapstructure:"ReadinessMaxActiveJobs"`
+	// 동시에 실행하는 최대 job 수 (기본 4)와 대기열 크기 (기본 100)
+	MaxConcurrentJobs int `mapstructure:"MaxConcurrentJobs"`
+	MaxQueuedJobs     int `mapstructure:"MaxQueuedJobs"`
+	// 사용자별 실행 중이거나 대기 중인 최대 job 수. 0이면 제한하지 않는다.
+	MaxJobsPerUser int `mapstructure:"MaxJobsPerUser"`
+	// 대기열이 가득 찼을 때 응답하는 Retry-After (기본 30s)
+	QueueRetryAfter time.Duration `mapstructure:"QueueRetryAfter"`
+	// 요청 tuning의 최대값. 0이나 빈 값이면 제한하지 않는다.
+	MaxTransfers          int    `mapstructure:"MaxTransfers"`
+	MaxCheckers           int    `mapstructure:"MaxCheckers"`
+	MaxMultiThreadStreams int    `mapstructure:"MaxMultiThreadStreams"`
+	MaxUploadConcurrency  int    `mapstructure:"MaxUploadConcurrency"`
+	MaxBufferSize         string `mapstructure:"MaxBufferSize"`
+	MaxChunkSize          string `mapstructure:"MaxChunkSize"`
+	// job별 대역폭 상한 (초당 byte, 예: 50M). 요청에 bwLimit가 없으면 이 값을 적용한다.
+	MaxJobBandwidth string `mapstructure:"MaxJobBandwidth"`
+	// local storageType 사용 여부 (기본 false). 사용하면 LocalStorageRoot 아래 경로만 허용한다.
+	LocalStorageEnabled bool   `mapstructure:"LocalStorageEnabled"`
+	LocalStorageRoot    string `mapstructure:"LocalStorageRoot"`
+	// true이면 sftp 요청에 knownHosts가 없을 때 서버 host key를 확인하지 않는다 (기본 false, 개발용).
+	SftpInsecureHostKey bool `mapstructure:"SftpInsecureHostKey"`
+
+	routeTimeouts map[string]time.Duration
+}
+
+// 설정하지 않은 값의 기본값
+var defaults = map[string]interface{}{
+	"ListenAddress":         ":8901",
+	"ReadTimeout":           "10s",
+	"WriteTimeout":          "10s",
+	"IdleTimeout":           "120s",
+	"RouteTimeouts":         "/v1/migration/operations/list=60s",
+	"CorsAllowedOrigins":    "*",
+	"CorsAllowedMethods":    "GET,POST,PUT,DELETE,OPTIONS",
+	"CorsAllowedHeaders":    "authorization,content-type,accept,origin,cache-control,x-requested-with,ulang,accept-language,traceparent,tracestate,baggage,x-request-id",
+	"MaxRequestBodySize":    1 << 20,
+	"SwaggerEnabled":        true,
+	"MetricsEnabled":        true,
+	"ShutdownGracePeriod":   "25s",
+	"MaxConcurrentJobs":     4,
+	"MaxQueuedJobs":         100,
+	"QueueRetryAfter":       "30s",
+	"MaxTransfers":          16,
+	"MaxCheckers":           32,
+	"MaxMultiThreadStreams": 8,
+	"MaxUploadConcurrency":  8,
+	"MaxBufferSize":         "64M",
+	"MaxChunkSize":          "128M",
 }
 
-func loadEnvVariables() (config *envConfigs) {
-	viper.AddConfigPath(".")
-	viper.SetConfigName("config")
-	viper.SetConfigType("env")
+// flag, 환경 변수, 설정 파일, 기본값 순으로 적용한다.
+// 설정 파일은 --config 또는 CONFIG_FILE로 지정하고, 없으면 ./config.env를 읽는다.
+// ./config.env가 없으면 환경 변수와 flag만 사용한다.
+// 환경 변수와 config.env의 ${ENV}가 비어 있으면 설정하지 않은 것으로 본다.
+func loadEnvVariables(args []string) (*envConfigs, error) {
+	v := viper.NewWithOptions(viper.EnvKeyReplacer(newEnvKeyReplacer()))
+	v.AutomaticEnv()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	flags := newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if key, ok := f.Annotations["key"]; ok {
+			v.BindPFlag(key[0], f)
+		}
+	})
 
-	if err := viper.ReadInConfig(); err != nil {
-		if os.Getenv("UNIT_TEST") == "1" {
-			log.Warnf("Skipping config file read in test: %v", err)
-			return &envConfigs{}
+	file, _ := flags.GetString("config")
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	values, err := readConfigFile(file)
+	if err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("reading config file: %w", err)
 		}
-		log.Fatal("Error reading env file", err)
+		slog.Info("config file not found, using environment variables and flags", "error", err)
+	}
+	if err := v.MergeConfigMap(values); err != nil {
+		return nil, err
 	}
-	if err := viper.Unmarshal(&config); err != nil {
-		log.Fatal(err)
+
+	var config envConfigs
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, err
 	}
 	config.PrivateKey = strings.ReplaceAll(config.PrivateKey, `\n`, "\n")
-	return
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// 기본 이름(MaxQueuedJobs는 MAX_QUEUED_JOBS)과 다른 환경 변수 이름
+var envNames = map[string]string{
+	"HmacKey":       "MIG_HMAC_KEY",
+	"PrivateKey":    "MIG_PRIVATE_KEY",
+	"PrivateKeyDir": "MIG_PRIVATE_KEY_DIR",
+}
+
+func envName(key string) string {
+	if name, ok := envNames[key]; ok {
+		return name
+	}
+	return strings.ToUpper(strings.ReplaceAll(flagName(key), "-", "_"))
+}
+
+// viper는 설정 항목을 대문자(MAXQUEUEDJOBS)로 바꿔 환경 변수를 찾으므로 환경 변수 이름으로 바꾼다.
+type envKeyReplacer map[string]string
+
+func newEnvKeyReplacer() envKeyReplacer {
+	replacer := envKeyReplacer{}
+	fields := reflect.TypeOf(envConfigs{})
+	for i := 0; i < fields.NumField(); i++ {
+		if key := fields.Field(i).Tag.Get("mapstructure"); key != "" {
+			replacer[strings.ToUpper(key)] = envName(key)
+		}
+	}
+	return replacer
+}
+
+func (r envKeyReplacer) Replace(key string) string {
+	if name, ok := r[key]; ok {
+		return name
+	}
+	return key
+}
+
+// 설정 파일의 비어 있지 않은 값
+func readConfigFile(file string) (map[string]interface{}, error) {
+	fileViper := viper.New()
+	if file != "" {
+		fileViper.SetConfigFile(file)
+	} else {
+		fileViper.AddConfigPath(".")
+		fileViper.SetConfigName("config")
+		fileViper.SetConfigType("env")
+	}
+	if err := fileViper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	for _, key := range fileViper.AllKeys() {
+		if value := fileViper.Get(key); value != nil && value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// 설정 항목마다 ListenAddress는 --listen-address 같은 flag를 만든다.
+func newFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("kps-migration-api", pflag.ContinueOnError)
+	flags.String("config", "", "config file (env, yaml, json)")
+
+	fields := reflect.TypeOf(envConfigs{})
+	for i := 0; i < fields.NumField(); i++ {
+		key := fields.Field(i).Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		name := flagName(key)
+		flags.String(name, "", key)
+		flags.SetAnnotation(name, "key", []string{key})
+	}
+	return flags
+}
+
+var upperPattern = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func flagName(key string) string {
+	return strings.ToLower(upperPattern.ReplaceAllString(key, "$1-$2"))
+}
+
+var logLevels = map[string]bool{"": true, "debug": true, "info": true, "warn": true, "error": true}
+
+var tracingExporters = map[string]bool{"": true, "none": true, "otlp": true, "stdout": true}
+
+// 잘못된 설정을 모두 모아 반환한다.
+func (config *envConfigs) validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	for _, toggle := range []struct{ key, value string }{
+		{"IsEncryption", config.IsEncryption},
+		{"IsResponseEncryption", config.IsResponseEncryption},
+	} {
+		if toggle.value != "" && toggle.value != "true" && toggle.value != "false" {
+			invalid(toggle.key, "must be true or false, got %q", toggle.value)
+		}
+	}
+	for _, duration := range []struct{ key, value string }{
+		{"PayloadMaxAge", config.PayloadMaxAge},
+		{"PayloadMaxSkew", config.PayloadMaxSkew},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d <= 0 {
+			invalid(duration.key, "must be a positive duration, got %q", duration.value)
+		}
+	}
+	if !logLevels[strings.ToLower(config.LogLevel)] {
+		invalid("LogLevel", "must be one of debug, info, warn, error, got %q", config.LogLevel)
+	}
+	if !tracingExporters[config.TracingExporter] {
+		invalid("TracingExporter", "must be one of none, otlp, stdout, got %q", config.TracingExporter)
+	}
+	if config.TracingSampleRatio != "" {
+		if ratio, err := strconv.ParseFloat(config.TracingSampleRatio, 64); err != nil || ratio < 0 || ratio > 1 {
+			invalid("TracingSampleRatio", "must be between 0 and 1, got %q", config.TracingSampleRatio)
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(config.ListenAddress); err != nil {
+		invalid("ListenAddress", "%v", err)
+	}
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"ReadTimeout", config.ReadTimeout},
+		{"WriteTimeout", config.WriteTimeout},
+		{"IdleTimeout", config.IdleTimeout},
+		{"ShutdownGracePeriod", config.ShutdownGracePeriod},
+		{"QueueRetryAfter", config.QueueRetryAfter},
+	} {
+		if timeout.value < 0 {
+			invalid(timeout.key, "must not be negative, got %s", timeout.value)
+		}
+	}
+	routeTimeouts, err := parseRouteTimeouts(config.RouteTimeouts)
+	if err != nil {
+		invalid("RouteTimeouts", "%v", err)
+	}
+	config.routeTimeouts = routeTimeouts
+
+	config.CorsAllowedOrigins = trimList(config.CorsAllowedOrigins)
+	config.CorsAllowedMethods = trimList(config.CorsAllowedMethods)
+	config.CorsAllowedHeaders = trimList(config.CorsAllowedHeaders)
+	if len(config.CorsAllowedOrigins) == 0 {
+		invalid("CorsAllowedOrigins", "must not be empty")
+	}
+	if config.MaxConcurrentJobs <= 0 {
+		invalid("MaxConcurrentJobs", "must be positive, got %d", config.MaxConcurrentJobs)
+	}
+	for _, limit := range []struct {
+		key   string
+		value int
+	}{
+		{"MaxQueuedJobs", config.MaxQueuedJobs},
+		{"MaxJobsPerUser", config.MaxJobsPerUser},
+		{"ReadinessMaxActiveJobs", config.ReadinessMaxActiveJobs},
+		{"MaxTransfers", config.MaxTransfers},
+		{"MaxCheckers", config.MaxCheckers},
+		{"MaxMultiThreadStreams", config.MaxMultiThreadStreams},
+		{"MaxUploadConcurrency", config.MaxUploadConcurrency},
+	} {
+		if limit.value < 0 {
+			invalid(limit.key, "must not be negative, got %d", limit.value)
+		}
+	}
+	for _, size := range []struct{ key, value string }{
+		{"MaxBufferSize", config.MaxBufferSize},
+		{"MaxChunkSize", config.MaxChunkSize},
+		{"MaxJobBandwidth", config.MaxJobBandwidth},
+	} {
+		if _, err := ParseSize(size.value); err != nil {
+			invalid(size.key, "%v", err)
+		}
+	}
+	if config.MaxRequestBodySize <= 0 {
+		invalid("MaxRequestBodySize", "must be positive, got %d", config.MaxRequestBodySize)
+	}
+
+	if config.LocalStorageEnabled && !filepath.IsAbs(config.LocalStorageRoot) {
+		invalid("LocalStorageRoot", "must be an absolute path when LocalStorageEnabled is true, got %q", config.LocalStorageRoot)
+	}
+
+	if (config.TlsCertFile == "") != (config.TlsKeyFile == "") {
+		invalid("TlsCertFile", "TlsCertFile and TlsKeyFile must be set together")
+	}
+	for _, file := range []struct{ key, value string }{
+		{"TlsCertFile", config.TlsCertFile},
+		{"TlsKeyFile", config.TlsKeyFile},
+	} {
+		if file.value == "" {
+			continue
+		}
+		if _, err := os.Stat(file.value); err != nil {
+			invalid(file.key, "%v", err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rclone 크기 형식(예: 16M, 1G)을 byte로 바꾼다. 빈 값은 0이다.
+func ParseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	var size fs.SizeSuffix
+	if err := size.Set(value); err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("size must not be negative, got %q", value)
+	}
+	return int64(size), nil
+}
+
+func parseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range trimList(strings.Split(value, ",")) {
+		route, duration, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("%q must be <route template>=<duration>", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%q must have a positive duration", entry)
+		}
+		timeouts[strings.TrimSpace(route)] = d
+	}
+	return timeouts, nil
+}
+
+func trimList(values []string) []string {
+	var trimmed []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// route template(예: /v1/migration/operations/list)의 처리 timeout
+func (config *envConfigs) RouteTimeout(route string) (time.Duration, bool) {
+	d, ok := config.routeTimeouts[route]
+	return d, ok
 }
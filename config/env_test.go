@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return file
+}
+
+func TestLoadEnvVariables_Defaults(t *testing.T) {
+	// 비어 있는 ${ENV} 값은 기본값을 덮어쓰지 않는다.
+	file := writeConfigFile(t, "config.env", "ListenAddress=\nIsEncryption=true\n")
+
+	env, err := loadEnvVariables([]string{"--config", file})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if env.ListenAddress != ":8901" || env.ReadTimeout != 10*time.Second || env.IdleTimeout != 120*time.Second {
+		t.Fatalf("unexpected server defaults: %+v", env)
+	}
+	if env.MaxRequestBodySize != 1<<20 || !env.SwaggerEnabled || !env.MetricsEnabled || env.IsEncryption != "true" {
+		t.Fatalf("unexpected defaults: %+v", env)
+	}
+	if len(env.CorsAllowedOrigins) != 1 || env.CorsAllowedOrigins[0] != "*" || len(env.CorsAllowedMethods) != 5 {
+		t.Fatalf("unexpected cors defaults: %v %v", env.CorsAllowedOrigins, env.CorsAllowedMethods)
+	}
+	if d, ok := env.RouteTimeout("/v1/migration/operations/list"); !ok || d != time.Minute {
+		t.Fatalf("expected list route timeout, got %v %v", d, ok)
+	}
+}
+
+func TestLoadEnvVariables_FileAndFlags(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", strings.Join([]string{
+		"ListenAddress: 127.0.0.1:9000",
+		"WriteTimeout: 30s",
+		"CorsAllowedOrigins: https://portal.example.com, https://admin.example.com",
+		"SwaggerEnabled: false",
+	}, "\n"))
+
+	env, err := loadEnvVariables([]string{"--config", file, "--listen-address", ":9443", "--max-request-body-size", "4096"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// flag가 설정 파일보다 우선한다.
+	if env.ListenAddress != ":9443" || env.MaxRequestBodySize != 4096 {
+		t.Fatalf("expected flag values, got %q %d", env.ListenAddress, env.MaxRequestBodySize)
+	}
+	if env.WriteTimeout != 30*time.Second || env.SwaggerEnabled {
+		t.Fatalf("expected file values, got %v %v", env.WriteTimeout, env.SwaggerEnabled)
+	}
+	if len(env.CorsAllowedOrigins) != 2 || env.CorsAllowedOrigins[1] != "https://admin.example.com" {
+		t.Fatalf("unexpected cors origins: %v", env.CorsAllowedOrigins)
+	}
+}
+
+func TestLoadEnvVariables_Invalid(t *testing.T) {
+	file := writeConfigFile(t, "config.env", strings.Join([]string{
+		"ListenAddress=8901",
+		"ReadTimeout=soon",
+	}, "\n"))
+	if _, err := loadEnvVariables([]string{"--config", file}); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+
+	file = writeConfigFile(t, "config.env", strings.Join([]string{
+		"ListenAddress=8901",
+		"RouteTimeouts=/v1/migration/operations/list",
+		"MaxRequestBodySize=-1",
+		"TlsCertFile=/missing/cert.pem",
+		"LogLevel=verbose",
+		"ReadinessMaxActiveJobs=-1",
+		"MaxChunkSize=big",
+		"LocalStorageEnabled=true",
+		"LocalStorageRoot=data",
+	}, "\n"))
+	_, err := loadEnvVariables([]string{"--config", file})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	// 잘못된 설정을 한 번에 알려준다.
+	for _, key := range []string{"ListenAddress", "RouteTimeouts", "MaxRequestBodySize", "TlsCertFile", "LogLevel", "ReadinessMaxActiveJobs", "MaxChunkSize", "LocalStorageRoot"} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Fatalf("expected %s in error, got %v", key, err)
+		}
+	}
+
+	if _, err := loadEnvVariables([]string{"--unknown"}); err == nil {
+		t.Fatal("expected error for unknown flag")
+	}
+	if _, err := loadEnvVariables([]string{"--config", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Fatal("expected error for missing config file")
+	}
+}
+
+func TestLoadEnvVariables_Environment(t *testing.T) {
+	file := writeConfigFile(t, "config.env", "WriteTimeout=30s\nMaxQueuedJobs=50\n")
+	t.Setenv("WRITE_TIMEOUT", "45s")
+	t.Setenv("LISTEN_ADDRESS", ":9100")
+	t.Setenv("MIG_PRIVATE_KEY_DIR", "/keys")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://portal.example.com,https://admin.example.com")
+	t.Setenv("AUTH_DISABLED", "true")
+	t.Setenv("MAX_QUEUED_JOBS", "")
+
+	env, err := loadEnvVariables([]string{"--config", file, "--listen-address", ":9443"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 환경 변수는 설정 파일보다, flag는 환경 변수보다 우선한다.
+	if env.WriteTimeout != 45*time.Second || env.ListenAddress != ":9443" {
+		t.Fatalf("expected env and flag values, got %v %q", env.WriteTimeout, env.ListenAddress)
+	}
+	if env.PrivateKeyDir != "/keys" || !env.AuthDisabled || len(env.CorsAllowedOrigins) != 2 {
+		t.Fatalf("unexpected env values: %q %v %v", env.PrivateKeyDir, env.AuthDisabled, env.CorsAllowedOrigins)
+	}
+	// 비어 있는 환경 변수는 설정 파일 값을 덮어쓰지 않는다.
+	if env.MaxQueuedJobs != 50 {
+		t.Fatalf("expected file value for empty env, got %d", env.MaxQueuedJobs)
+	}
+}
+
+func TestLoadEnvVariables_WithoutConfigFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MAX_CONCURRENT_JOBS", "8")
+
+	env, err := loadEnvVariables([]string{"--log-level", "debug"})
+	if err != nil {
+		t.Fatalf("expected ./config.env to be optional, got %v", err)
+	}
+	if env.MaxConcurrentJobs != 8 || env.LogLevel != "debug" || env.ListenAddress != ":8901" {
+		t.Fatalf("unexpected values without config file: %+v", env)
+	}
+
+	// 환경 변수의 잘못된 값도 검증한다.
+	t.Setenv("MAX_CONCURRENT_JOBS", "0")
+	if _, err := loadEnvVariables(nil); err == nil || !strings.Contains(err.Error(), "MaxConcurrentJobs:") {
+		t.Fatalf("expected MaxConcurrentJobs error, got %v", err)
+	}
+}
+
+// config.env와 환경 변수 이름이 같아야 config.env 없이도 같은 설정을 사용할 수 있다.
+func TestEnvName_MatchesConfigFile(t *testing.T) {
+	data, err := os.ReadFile("../config.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		if want := "${" + envName(key) + "}"; value != want {
+			t.Errorf("%s: expected %s in config.env, got %s", key, want, value)
+		}
+	}
+}
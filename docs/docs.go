@@ -15,9 +15,390 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/actuator/health": {
+            "get": {
+                "description": "Report liveness and each readiness check (shutdown, encryptionKey, jobStore, activeJobs).\nReturns 503 when any check is DOWN. \"activeJobs\" reports running and queued jobs in \"details\" and is DOWN when the job queue is full.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Report health checks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.HealthReport"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.HealthReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/migration/encryption/jwks.json": {
+            "get": {
+                "description": "Publish the RSA public keys used to wrap the AES key of model.HybridPayload as a JWKS.\nSend the \"kid\" of the chosen key in the payload. The first key is the preferred one.\nA key with \"exp\" (unix time) is being retired and is rejected after that time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Encryption"
+                ],
+                "summary": "Publish encryption public keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Jwks"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/migration/jobs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check the state, start and end time and the final error or result of a migration job.\nA failed job also reports \"errorCode\" (AUTH_FAILED, BUCKET_NOT_FOUND, ENDPOINT_UNREACHABLE, ...).\nA queued job reports its \"queuePosition\" (1 runs next).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Migration"
+                ],
+                "summary": "Check migration job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobStatus"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stop a running, queued or paused migration job.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Migration"
+                ],
+                "summary": "Cancel migration job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobStatus"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/migration/jobs/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream progress snapshots of a migration job as Server-Sent Events.\nA \"progress\" event is sent periodically with rclone core/stats of the job.\nA \"done\" event with the final job status is sent when the job ends, then the stream is closed.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Migration"
+                ],
+                "summary": "Stream migration job progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.JobProgress"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/migration/jobs/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pause a running migration job. Files already transferred are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Migration"
+                ],
+                "summary": "Pause migration job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobStatus"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/migration/jobs/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Resume a paused migration job, or a job interrupted by a server shutdown. Files already at the destination are skipped.\nThe job is queued when no slot is free, and 429 with Retry-After is returned when the queue is full.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Migration"
+                ],
+                "summary": "Resume migration job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "job id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobStatus"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/v1/migration/operations/list": {
             "post": {
-                "description": "Check bucket list.\nExample request body before encoding :\n{\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.com\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"tpsxj0812\",\n\"bucket\": \"\"\n}",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check bucket list.\nFor sftp and local storage the directories under \"bucket\" are listed.\n\"storageType\" is one of s3, azureblob, google cloud storage (gcs), swift, sftp and local.\n\"local\" is rejected unless the server enables it, and its \"bucket\" path must be under the configured local root.\nNon-S3 storages take their settings from \"azureblob\", \"gcs\", \"swift\" or \"sftp\" (see model.StorageConfig).\n\"sftp\" also requires \"knownHosts\", the OpenSSH known_hosts lines of the server, unless the server disables host key checking.\nS3 storages also accept \"provider\" (AWS, Ceph, Minio, Other, ...), \"region\", \"forcePathStyle\", \"v2Auth\", \"locationConstraint\" and \"acl\".\nStorage failures are reported in error.code as AUTH_FAILED, PERMISSION_DENIED, BUCKET_NOT_FOUND, QUOTA_EXCEEDED, RATE_LIMITED, TLS_ERROR, ENDPOINT_UNREACHABLE or RCLONE_ERROR.\nCalls denied by the RBAC policy return 403 with error.code FORBIDDEN. Requires the \"bucket:list\" permission for the storage endpoints.\nWith response encryption enabled the body is model.EncryptedPayload, encrypted with the AES key of the request.\nMalformed or undecryptable bodies return 400 with error.code INVALID_REQUEST. An expired encrypted request returns 401 with REQUEST_EXPIRED and a reused nonce returns 409 with REPLAYED_REQUEST.\nExample request body before encoding :\n{\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.com\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"tpsxj0812\",\n\"bucket\": \"\"\n}",
                 "consumes": [
                     "application/json"
                 ],
@@ -43,22 +424,79 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "object"
-                            }
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "507": {
+                        "description": "Insufficient Storage",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
@@ -66,7 +504,12 @@ const docTemplate = `{
         },
         "/v1/migration/sync/copy": {
             "post": {
-                "description": "Copying Between Storage.\nExample request body before encoding :\n{\n\"src\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.co.kr\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abc\"\n},\n\"dst\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.com\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abcd\"\n}\n}",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Copying Between Storage. Objects missing from src are kept in dst.\nThe request body, responses and error codes are the same as /v1/migration/sync/sync, except that it requires the \"migration:copy\" permission for the storage endpoints.",
                 "consumes": [
                     "application/json"
                 ],
@@ -89,16 +532,88 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobAccepted"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "507": {
+                        "description": "Insufficient Storage",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
@@ -106,7 +621,12 @@ const docTemplate = `{
         },
         "/v1/migration/sync/sync": {
             "post": {
-                "description": "Synchronize between storage.\nExample request body before encoding :\n{\n\"src\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.co.kr\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abc\"\n},\n\"dst\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.com\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abcd\"\n}\n}",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Synchronize between storage. Objects missing from src are deleted from dst, and a dry run lists them as planned deletes.\nThe request body is model.SyncConfig. Its fields (storageType, dryRun, filter, priority, tuning) are documented on model.SyncConfig and model.StorageConfig.\nThe job runs in the background. Check its status with /v1/migration/jobs/{id}.\nStorage failures are reported in error.code as AUTH_FAILED, PERMISSION_DENIED, BUCKET_NOT_FOUND, QUOTA_EXCEEDED, RATE_LIMITED, TLS_ERROR, ENDPOINT_UNREACHABLE or RCLONE_ERROR.\nCalls denied by the RBAC policy return 403 with error.code FORBIDDEN. Requires the \"migration:sync\" permission for the storage endpoints.\nAt most MaxConcurrentJobs jobs run at once. Other jobs wait in a queue and report \"queuePosition\".\nA full queue or too many active jobs for the user returns 429 with Retry-After and error.code QUEUE_FULL or USER_JOB_LIMIT.\nTuning values above the server maximums return 400 with error.code INVALID_TUNING.\nWith response encryption enabled the body is model.EncryptedPayload, encrypted with the AES key of the request.\nMalformed or undecryptable bodies return 400 with error.code INVALID_REQUEST. An expired encrypted request returns 401 with REQUEST_EXPIRED and a reused nonce returns 409 with REPLAYED_REQUEST.\nExample request body before encoding :\n{\n\"src\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.co.kr\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abc\"\n},\n\"dst\": {\n\"storageType\": \"s3\",\n\"endpoint\": \"http://url.com\",\n\"accessKeyId\": \"admin\",\n\"secretAccessKey\": \"admin\",\n\"bucket\": \"abcd\",\n\"provider\": \"Ceph\",\n\"region\": \"kr-central-1\",\n\"forcePathStyle\": true\n},\n\"dryRun\": false,\n\"priority\": \"normal\",\n\"tuning\": {\n\"transfers\": 8,\n\"checkers\": 16,\n\"chunkSize\": \"64M\",\n\"uploadConcurrency\": 4,\n\"bwLimit\": \"50M\"\n},\n\"filter\": {\n\"exclude\": [\"*.log\", \"tmp/**\"],\n\"rules\": [\"- *.bak\", \"+ **\"],\n\"minSize\": \"1k\",\n\"maxSize\": \"10G\",\n\"minAge\": \"1h\",\n\"maxAge\": \"30d\"\n}\n}",
                 "consumes": [
                     "application/json"
                 ],
@@ -129,16 +649,88 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/model.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/model.JobAccepted"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
+                        }
+                    },
+                    "507": {
+                        "description": "Insufficient Storage",
+                        "schema": {
+                            "$ref": "#/definitions/model.Response"
                         }
                     }
                 }
@@ -146,9 +738,58 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.DryRunReport": {
+            "type": "object",
+            "properties": {
+                "copy": {
+                    "$ref": "#/definitions/model.PlannedChanges"
+                },
+                "delete": {
+                    "$ref": "#/definitions/model.PlannedChanges"
+                },
+                "update": {
+                    "$ref": "#/definitions/model.PlannedChanges"
+                }
+            }
+        },
+        "model.HealthCheck": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.HealthReport": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.HealthCheck"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "model.HybridPayload": {
             "type": "object",
             "properties": {
+                "alg": {
+                    "type": "string"
+                },
                 "data": {
                     "type": "string"
                 },
@@ -158,8 +799,292 @@ const docTemplate = `{
                 },
                 "key": {
                     "type": "string"
+                },
+                "kid": {
+                    "description": "key를 암호화한 RSA 키. 없으면 default 키를 사용한다.",
+                    "type": "string"
+                },
+                "nonce": {
+                    "description": "AES-256-GCM 요청의 재전송 방지 nonce. AES-CBC는 암호화된 객체 안에 nonce를 넣는다.",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.JobAccepted": {
+            "type": "object",
+            "properties": {
+                "jobId": {
+                    "type": "string"
+                },
+                "queuePosition": {
+                    "type": "integer"
+                },
+                "state": {
+                    "$ref": "#/definitions/model.JobState"
+                }
+            }
+        },
+        "model.JobAction": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.JobProgress": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "checks": {
+                    "type": "integer"
+                },
+                "deletes": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "eta": {
+                    "type": "number"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "speed": {
+                    "type": "number"
+                },
+                "state": {
+                    "$ref": "#/definitions/model.JobState"
+                },
+                "totalBytes": {
+                    "type": "integer"
+                },
+                "totalChecks": {
+                    "type": "integer"
+                },
+                "totalTransfers": {
+                    "type": "integer"
+                },
+                "transferring": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TransferProgress"
+                    }
+                },
+                "transfers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.JobState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "queued",
+                "running",
+                "paused",
+                "success",
+                "error",
+                "cancelled",
+                "interrupted"
+            ],
+            "x-enum-varnames": [
+                "JobStatePending",
+                "JobStateQueued",
+                "JobStateRunning",
+                "JobStatePaused",
+                "JobStateSuccess",
+                "JobStateError",
+                "JobStateCancelled",
+                "JobStateInterrupted"
+            ]
+        },
+        "model.JobStatus": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.JobAction"
+                    }
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "endTime": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errorCode": {
+                    "type": "string"
+                },
+                "jobId": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "plan": {
+                    "$ref": "#/definitions/model.DryRunReport"
+                },
+                "priority": {
+                    "type": "string"
+                },
+                "queuePosition": {
+                    "description": "queued 상태일 때 대기열에서의 순서 (1부터)",
+                    "type": "integer"
+                },
+                "startTime": {
+                    "type": "string"
+                },
+                "state": {
+                    "$ref": "#/definitions/model.JobState"
+                }
+            }
+        },
+        "model.Jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Jwks": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Jwk"
+                    }
+                }
+            }
+        },
+        "model.PlannedChanges": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "objects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PlannedObject"
+                    }
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.PlannedObject": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Response": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "$ref": "#/definitions/model.ResponseError"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ResponseError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "message": {
+                    "type": "string"
                 }
             }
+        },
+        "model.TransferProgress": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "eta": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "percentage": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "speed": {
+                    "type": "number"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "OIDC access token from oauth2-proxy, as \"Bearer \u003ctoken\u003e\". Required unless AuthDisabled is set.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
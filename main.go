@@ -6,6 +6,7 @@ import (
 	"kps-migration-api/api"
 	_ "kps-migration-api/api"
 	_ "kps-migration-api/docs"
+	"log/slog"
 	_ "net/http"
 
 	_ "github.com/rclone/rclone/librclone/librclone"
@@ -31,8 +32,13 @@ import (
 	_ "github.com/rclone/rclone/lib/plugin"
 )
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description OIDC access token from oauth2-proxy, as "Bearer <token>". Required unless AuthDisabled is set.
 func main() {
-	println("Let's start to kps migration api")
+	api.SetupLogging()
+	slog.Info("Let's start to kps migration api")
 
 	//go
 	api.ProcessREST()
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// 감사 로그 status
+const (
+	AuditStatusSuccess   = "success"
+	AuditStatusError     = "error"
+	AuditStatusCancelled = "cancelled"
+	// 권한이 없어 거부된 요청
+	AuditStatusDenied = "denied"
+	// 잘못된 요청이거나 작업을 시작하지 못한 요청
+	AuditStatusRejected = "rejected"
+)
+
+// 마이그레이션 요청 1건의 감사 기록. 인증 정보는 포함하지 않는다.
+type AuditRecord struct {
+	Time       time.Time     `json:"time"`
+	Operation  string        `json:"operation"`
+	JobId      string        `json:"jobId,omitempty"`
+	Caller     *AuditCaller  `json:"caller,omitempty"`
+	RemoteAddr string        `json:"remoteAddr,omitempty"`
+	Src        *AuditStorage `json:"src,omitempty"`
+	Dst        *AuditStorage `json:"dst,omitempty"`
+	DryRun     bool          `json:"dryRun"`
+	StartTime  time.Time     `json:"startTime"`
+	EndTime    time.Time     `json:"endTime"`
+	Status     string        `json:"status"`
+	ErrorCode  string        `json:"errorCode,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Bytes      int64         `json:"bytes"`
+	Objects    int64         `json:"objects"`
+	Deletes    int64         `json:"deletes"`
+}
+
+type AuditCaller struct {
+	Subject  string `json:"subject"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+type AuditStorage struct {
+	StorageType string `json:"storageType"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+}
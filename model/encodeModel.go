@@ -1,18 +1,45 @@
 package model
 
+// nonce는 요청마다 새로 만든 값(16~128자)으로, 같은 요청의 재전송을 막는다.
 type PayloadModelWithoutHMAC[T any] struct {
 	Data      T      `json:"data"`
 	Timestamp string `json:"timestamp"`
+	Nonce     string `json:"nonce,omitempty"`
 }
 
 type PayloadModelWithHMAC[T any] struct {
 	Data      T      `json:"data"`
 	Timestamp string `json:"timestamp"`
+	Nonce     string `json:"nonce,omitempty"`
 	Hmac_data string `json:"hmac_data"`
 }
+
+// HybridPayload 암호화 방식 (alg)
+const (
+	// 기본값. data는 PayloadModelWithHMAC을 AES-CBC(PKCS7)로 암호화한 값이다.
+	PayloadAlgAesCbc = "AES-CBC"
+	// data는 객체 json을 AES-256-GCM으로 암호화한 값이고 iv는 12바이트 GCM nonce이다.
+	// timestamp와 nonce는 associated data("<timestamp>.<nonce>")로 인증된다.
+	PayloadAlgAesGcm = "AES-256-GCM"
+)
+
 type HybridPayload struct {
 	EncryptedKey string `json:"key"`
 	//IV            []byte `json:"iv"`
 	IV            string `json:"iv"`
 	EncryptedData string `json:"data"`
+	Alg           string `json:"alg,omitempty"`
+	Timestamp     string `json:"timestamp,omitempty"`
+	// AES-256-GCM 요청의 재전송 방지 nonce. AES-CBC는 암호화된 객체 안에 nonce를 넣는다.
+	Nonce string `json:"nonce,omitempty"`
+	// key를 암호화한 RSA 키. 없으면 default 키를 사용한다.
+	Kid string `json:"kid,omitempty"`
+}
+
+// 암호화된 응답. 요청의 HybridPayload에서 복호화한 AES 키와 같은 alg로 암호화하므로 key는 포함하지 않는다.
+type EncryptedPayload struct {
+	Alg           string `json:"alg,omitempty"`
+	IV            string `json:"iv"`
+	EncryptedData string `json:"data"`
+	Timestamp     string `json:"timestamp,omitempty"`
 }
@@ -0,0 +1,160 @@
+package model
+
+import "time"
+
+type JobState string
+
+const (
+	// 등록했지만 아직 scheduler가 실행이나 대기를 정하지 않은 job
+	JobStatePending JobState = "pending"
+	// 실행할 자리를 기다리는 job
+	JobStateQueued    JobState = "queued"
+	JobStateRunning   JobState = "running"
+	JobStatePaused    JobState = "paused"
+	JobStateSuccess   JobState = "success"
+	JobStateError     JobState = "error"
+	JobStateCancelled JobState = "cancelled"
+	// 서버 종료로 중지된 job. paused와 같이 재개할 수 있다.
+	JobStateInterrupted JobState = "interrupted"
+)
+
+// 더 이상 상태가 바뀌지 않는 job인지 확인한다.
+func (s JobState) IsFinal() bool {
+	return s == JobStateSuccess || s == JobStateError || s == JobStateCancelled
+}
+
+const (
+	JobActionCancel = "cancel"
+	JobActionPause  = "pause"
+	JobActionResume = "resume"
+	// 서버 종료
+	JobActionInterrupt = "interrupt"
+)
+
+// 대기열 우선순위. 높은 우선순위의 job이 먼저 실행되고, 같은 우선순위는 요청 순서대로 실행된다.
+const (
+	JobPriorityLow    = "low"
+	JobPriorityNormal = "normal"
+	JobPriorityHigh   = "high"
+)
+
+type JobAction struct {
+	Action string    `json:"action"`
+	Time   time.Time `json:"time"`
+}
+
+type JobStatus struct {
+	JobId     string                 `json:"jobId"`
+	Operation string                 `json:"operation"`
+	Owner     string                 `json:"owner,omitempty"`
+	DryRun    bool                   `json:"dryRun"`
+	State     JobState               `json:"state"`
+	Priority  string                 `json:"priority,omitempty"`
+	StartTime time.Time              `json:"startTime"`
+	EndTime   *time.Time             `json:"endTime,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	ErrorCode string                 `json:"errorCode,omitempty"`
+	Output    map[string]interface{} `json:"output,omitempty"`
+	Actions   []JobAction            `json:"actions,omitempty"`
+	Plan      *DryRunReport          `json:"plan,omitempty"`
+	// queued 상태일 때 대기열에서의 순서 (1부터)
+	QueuePosition int `json:"queuePosition,omitempty"`
+}
+
+// 서버 종료 시 저장하고 시작 시 다시 등록하는 재개 가능한 job.
+// 재개한 job이 끝날 때 감사 로그와 전송량을 이어서 남길 수 있게 감사 기록과 지금까지의 전송량을 함께 저장한다.
+type JobCheckpoint struct {
+	Status      JobStatus         `json:"status"`
+	Method      string            `json:"method"`
+	Request     SyncRequest       `json:"request"`
+	AuditRecord *AuditRecord      `json:"auditRecord,omitempty"`
+	Transferred JobTransferTotals `json:"transferred"`
+}
+
+// JobCheckpointFile 형식. JobCheckpoint 목록을 kid의 RSA 키로 감싼 AES-256-GCM 키로 암호화한다.
+type EncryptedCheckpoint struct {
+	Kid           string `json:"kid"`
+	EncryptedKey  string `json:"encryptedKey"`
+	IV            string `json:"iv"`
+	EncryptedData string `json:"encryptedData"`
+}
+
+type JobAccepted struct {
+	JobId         string   `json:"jobId"`
+	State         JobState `json:"state"`
+	QueuePosition int      `json:"queuePosition,omitempty"`
+}
+
+// rclone job/status 요청 및 응답
+type RcloneJobRequest struct {
+	JobId int64 `json:"jobid"`
+}
+
+type RcloneJobStatus struct {
+	Id        int64                  `json:"id"`
+	Group     string                 `json:"group"`
+	StartTime time.Time              `json:"startTime"`
+	EndTime   time.Time              `json:"endTime"`
+	Finished  bool                   `json:"finished"`
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error"`
+	Output    map[string]interface{} `json:"output"`
+}
+
+// rclone core/stats 기반 진행 상황
+type JobProgress struct {
+	JobId          string             `json:"jobId"`
+	State          JobState           `json:"state"`
+	Bytes          int64              `json:"bytes"`
+	TotalBytes     int64              `json:"totalBytes"`
+	Speed          float64            `json:"speed"`
+	Eta            *float64           `json:"eta"`
+	Checks         int64              `json:"checks"`
+	TotalChecks    int64              `json:"totalChecks"`
+	Transfers      int64              `json:"transfers"`
+	TotalTransfers int64              `json:"totalTransfers"`
+	Deletes        int64              `json:"deletes"`
+	Errors         int64              `json:"errors"`
+	Transferring   []TransferProgress `json:"transferring"`
+}
+
+// 이전 실행(일시정지, 서버 종료 전)까지의 전송량. 재개한 job의 진행률에 더한다.
+type JobTransferTotals struct {
+	Bytes     int64 `json:"bytes"`
+	Checks    int64 `json:"checks"`
+	Transfers int64 `json:"transfers"`
+	Deletes   int64 `json:"deletes"`
+	Errors    int64 `json:"errors"`
+}
+
+type TransferProgress struct {
+	Name       string   `json:"name"`
+	Size       int64    `json:"size"`
+	Bytes      int64    `json:"bytes"`
+	Percentage int      `json:"percentage"`
+	Speed      float64  `json:"speed"`
+	Eta        *float64 `json:"eta"`
+}
+
+type RcloneStatsRequest struct {
+	Group string `json:"group"`
+}
+
+// dry run 결과로 변경 예정인 객체 목록
+type PlannedObject struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type PlannedChanges struct {
+	Count     int             `json:"count"`
+	Bytes     int64           `json:"bytes"`
+	Objects   []PlannedObject `json:"objects"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+type DryRunReport struct {
+	Copy   PlannedChanges `json:"copy"`
+	Update PlannedChanges `json:"update"`
+	Delete PlannedChanges `json:"delete"`
+}
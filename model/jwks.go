@@ -0,0 +1,17 @@
+package model
+
+// RFC 7517 JSON Web Key Set
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// exp는 키 폐기 시각(unix time)이다.
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Exp int64  `json:"exp,omitempty"`
+}
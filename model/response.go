@@ -0,0 +1,20 @@
+package model
+
+const (
+	ResponseStatusSuccess = "success"
+	ResponseStatusError   = "error"
+)
+
+// 모든 API 응답의 공통 형식. 성공하면 data를, 실패하면 error를 채운다.
+type Response struct {
+	Status string         `json:"status"`
+	Data   interface{}    `json:"data,omitempty"`
+	Error  *ResponseError `json:"error,omitempty"`
+}
+
+// code는 UI에서 분기할 수 있는 고정 값이고 message는 원인 메시지이다.
+type ResponseError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -1,21 +1,122 @@
 package model
 
+// storageType은 s3, azureblob, gcs, swift, sftp, local 중 하나이다.
+// storageType에 따라 s3는 accessKeyId, secretAccessKey, endpoint와 s3 옵션을,
+// 그 외 스토리지는 같은 이름의 설정을 사용한다.
+// bucket은 s3, gcs의 bucket, azureblob, swift의 container, sftp, local의 경로이다.
+// local은 서버에서 허용한 경우에만 LocalStorageRoot 아래 경로로 사용할 수 있다.
 type StorageConfig struct {
-	AccessKeyId     string `json:"accessKeyId"`
-	Bucket          string `json:"bucket"`
-	Endpoint        string `json:"endpoint"`
-	SecretAccessKey string `json:"secretAccessKey"`
-	StorageType     string `json:"storageType"`
+	AccessKeyId     string           `json:"accessKeyId"`
+	Bucket          string           `json:"bucket"`
+	Endpoint        string           `json:"endpoint"`
+	SecretAccessKey string           `json:"secretAccessKey"`
+	StorageType     string           `json:"storageType"`
+	AzureBlob       *AzureBlobConfig `json:"azureblob,omitempty"`
+	Gcs             *GcsConfig       `json:"gcs,omitempty"`
+	Swift           *SwiftConfig     `json:"swift,omitempty"`
+	Sftp            *SftpConfig      `json:"sftp,omitempty"`
+
+	// s3 옵션. 지정하지 않으면 rclone 기본값을 사용한다.
+	// provider는 rclone s3 provider 이름(AWS, Ceph, Minio, Other 등)이다.
+	Provider           string `json:"provider,omitempty"`
+	Region             string `json:"region,omitempty"`
+	ForcePathStyle     *bool  `json:"forcePathStyle,omitempty"`
+	V2Auth             *bool  `json:"v2Auth,omitempty"`
+	LocationConstraint string `json:"locationConstraint,omitempty"`
+	Acl                string `json:"acl,omitempty"`
+}
+
+// account와 key 또는 sasUrl 중 하나가 필요하다.
+type AzureBlobConfig struct {
+	Account string `json:"account"`
+	Key     string `json:"key"`
+	SasUrl  string `json:"sasUrl"`
+}
+
+// serviceAccountCredentials는 service account JSON key 파일 내용이다.
+type GcsConfig struct {
+	ServiceAccountCredentials string `json:"serviceAccountCredentials"`
+	ProjectNumber             string `json:"projectNumber"`
+}
+
+type SwiftConfig struct {
+	AuthUrl     string `json:"authUrl"`
+	User        string `json:"user"`
+	Key         string `json:"key"`
+	Tenant      string `json:"tenant"`
+	Domain      string `json:"domain"`
+	Region      string `json:"region"`
+	AuthVersion int    `json:"authVersion"`
+}
+
+// pass 또는 keyPem 중 하나가 필요하다.
+// knownHosts는 서버 host key의 OpenSSH known_hosts 형식 줄이다. 22가 아닌 port는 [host]:port로 쓴다.
+// 서버가 host key 확인을 끄지 않았으면 필요하다.
+type SftpConfig struct {
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	User       string `json:"user"`
+	Pass       string `json:"pass"`
+	KeyPem     string `json:"keyPem"`
+	KnownHosts string `json:"knownHosts,omitempty"`
 }
 
+// sync, copy 요청 본문. 두 API의 필드 설명은 여기와 StorageConfig에만 둔다.
 type SyncConfig struct {
 	Dst StorageConfig `json:"dst"`
 	Src StorageConfig `json:"src"`
+	// true이면 변경하지 않고 job 상태에 copy, update, delete 예정 목록을 보여준다. delete는 sync에만 있다.
+	DryRun bool          `json:"dryRun"`
+	Filter *FilterConfig `json:"filter,omitempty"`
+	// 대기열 우선순위 (low, normal, high). 기본 normal. 같은 우선순위는 요청 순서로 실행한다.
+	Priority string        `json:"priority,omitempty"`
+	Tuning   *TuningConfig `json:"tuning,omitempty"`
+}
+
+// job별 전송 설정. 비어 있는 값은 rclone 기본값을 사용한다.
+// 크기와 대역폭은 rclone 형식(예: 16M, 1G)이고 대역폭은 초당 byte이다.
+// chunkSize, uploadConcurrency는 s3 대상 storage에만 적용된다.
+// 서버 최대값을 넘으면 INVALID_TUNING으로 거절하고, bwLimit이 없으면 서버 최대 대역폭을 적용한다.
+type TuningConfig struct {
+	Transfers          int    `json:"transfers,omitempty"`
+	Checkers           int    `json:"checkers,omitempty"`
+	BufferSize         string `json:"bufferSize,omitempty"`
+	MultiThreadStreams int    `json:"multiThreadStreams,omitempty"`
+	ChunkSize          string `json:"chunkSize,omitempty"`
+	UploadConcurrency  int    `json:"uploadConcurrency,omitempty"`
+	BwLimit            string `json:"bwLimit,omitempty"`
+}
+
+// 마이그레이션 대상 객체 필터. rules는 rclone filter 규칙("+ glob", "- glob") 목록이다.
+// include와 exclude를 순서대로 조합하려면 rules를 사용한다.
+type FilterConfig struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Rules   []string `json:"rules,omitempty"`
+	MinSize string   `json:"minSize,omitempty"`
+	MaxSize string   `json:"maxSize,omitempty"`
+	MinAge  string   `json:"minAge,omitempty"`
+	MaxAge  string   `json:"maxAge,omitempty"`
 }
 
 type SyncRequest struct {
-	DstFs string `json:"dstFs"`
-	SrcFs string `json:"srcFs"`
+	DstFs  string        `json:"dstFs"`
+	SrcFs  string        `json:"srcFs"`
+	Async  bool          `json:"_async,omitempty"`
+	Group  string        `json:"_group,omitempty"`
+	Config *RcloneConfig `json:"_config,omitempty"`
+	Filter *RcloneFilter `json:"_filter,omitempty"`
+}
+
+// rclone 호출 단위로 적용되는 전역 옵션 (_config)
+type RcloneConfig struct {
+	DryRun             bool   `json:"DryRun,omitempty"`
+	Transfers          int    `json:"Transfers,omitempty"`
+	Checkers           int    `json:"Checkers,omitempty"`
+	BufferSize         string `json:"BufferSize,omitempty"`
+	MultiThreadStreams int    `json:"MultiThreadStreams,omitempty"`
+	MultiThreadSet     bool   `json:"MultiThreadSet,omitempty"`
+	BwLimitFile        string `json:"BwLimitFile,omitempty"`
 }
 
 type ListRequest struct {
@@ -23,3 +124,14 @@ type ListRequest struct {
 	Remote string `json:"remote"`
 	Opts   string `json:"opts"`
 }
+
+// rclone 호출 단위로 적용되는 필터 옵션 (_filter)
+type RcloneFilter struct {
+	IncludeRule []string `json:"IncludeRule,omitempty"`
+	ExcludeRule []string `json:"ExcludeRule,omitempty"`
+	FilterRule  []string `json:"FilterRule,omitempty"`
+	MinSize     string   `json:"MinSize,omitempty"`
+	MaxSize     string   `json:"MaxSize,omitempty"`
+	MinAge      string   `json:"MinAge,omitempty"`
+	MaxAge      string   `json:"MaxAge,omitempty"`
+}